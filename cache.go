@@ -0,0 +1,197 @@
+package confi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// CachedSource keeps the last known good values of Source in a local file.
+// Values are written to the file only after the whole configuration has been loaded successfully,
+// and are used (with a warning) when Source fails, e.g. when a remote service is unreachable at startup.
+// If Cipher is set, the whole cache file is encrypted with it.
+// CachedSource must be used by pointer, since it keeps fetched values until the load succeeds.
+type CachedSource struct {
+	Source Source
+
+	// Path of the cache file. It is created with 0600 permissions.
+	Path string
+
+	// Cipher encrypts the cache file, so that neither secrets nor other values of Source are stored in plain text.
+	// If it is nil and values contain secrets, the cache file is not written (and a warning is reported).
+	Cipher *Cipher
+
+	mu      sync.Mutex
+	pending map[string]any
+}
+
+func (s *CachedSource) GetValues(ctx context.Context) (map[string]any, error) {
+	values, err := s.Source.GetValues(ctx)
+	if err == nil {
+		s.mu.Lock()
+		s.pending = values
+		s.mu.Unlock()
+		return values, nil
+	}
+
+	if ctx.Err() != nil {
+		return nil, err
+	}
+
+	cached, cacheErr := s.read(ctx)
+	if cacheErr != nil {
+		if !errors.Is(cacheErr, os.ErrNotExist) {
+			Warn(ctx, Warning{Code: WarnCacheRead, Source: s.Path, Message: "read cache: " + cacheErr.Error()})
+		}
+
+		return nil, err
+	}
+
+	Warn(ctx, Warning{
+		Code:    WarnStaleCache,
+		Source:  s.String(),
+		Message: "using cached values: " + err.Error(),
+	})
+
+	return cached, nil
+}
+
+// Watch delegates to Source if it implements Watcher, otherwise it blocks until ctx is done.
+func (s *CachedSource) Watch(ctx context.Context) error {
+	if watcher, ok := s.Source.(Watcher); ok {
+		return watcher.Watch(ctx)
+	}
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (s *CachedSource) String() string {
+	return fmt.Sprintf("cached %s", s.Source)
+}
+
+// commitLoad writes values fetched by the last GetValues call to the cache file.
+// It is called after the configuration has been loaded successfully.
+func (s *CachedSource) commitLoad(ctx context.Context, schema *Schema) {
+	s.mu.Lock()
+	values := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+
+	if values == nil {
+		return
+	}
+
+	if err := s.write(values, schema); err != nil {
+		Warn(ctx, Warning{Code: WarnCacheWrite, Source: s.Path, Message: "write cache: " + err.Error()})
+	}
+}
+
+func (s *CachedSource) read(ctx context.Context) (map[string]any, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if text := strings.TrimSpace(string(data)); IsEncrypted(text) {
+		if s.Cipher == nil {
+			return nil, errors.New("encrypted cache found, but no key configured")
+		}
+
+		text, err = s.Cipher.Decrypt(text)
+		if err != nil {
+			return nil, errors.Wrap(err, "decrypt")
+		}
+
+		data = []byte(text)
+	}
+
+	return InputSource{Input: Reader{R: bytes.NewReader(data)}, Format: "yaml", Cipher: s.Cipher}.GetValues(ctx)
+}
+
+func (s *CachedSource) write(values map[string]any, schema *Schema) error {
+	if s.Cipher == nil && hasSecrets(values, schema, false) {
+		return errors.New("secret value found, but no key configured")
+	}
+
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return errors.Wrap(err, "marshal")
+	}
+
+	if s.Cipher != nil {
+		text, err := s.Cipher.Encrypt(string(data))
+		if err != nil {
+			return errors.Wrap(err, "encrypt")
+		}
+
+		data = []byte(text + "\n")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o700); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(s.Path), "."+filepath.Base(s.Path)+".*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), s.Path)
+}
+
+// loadCommitter is implemented by sources which need to know that the configuration has been loaded successfully.
+type loadCommitter interface {
+	commitLoad(ctx context.Context, schema *Schema)
+}
+
+// hasSecrets reports whether value contains scalar values of write-only properties.
+func hasSecrets(value any, schema *Schema, secret bool) bool {
+	secret = secret || schema != nil && schema.WriteOnly
+	switch value := value.(type) {
+	case map[string]any:
+		for key, item := range value {
+			if hasSecrets(item, schema.Lookup(key), secret) {
+				return true
+			}
+		}
+
+		return false
+
+	case []any:
+		var items *Schema
+		if schema != nil {
+			items = schema.Items
+		}
+
+		for _, item := range value {
+			if hasSecrets(item, items, secret) {
+				return true
+			}
+		}
+
+		return false
+
+	case nil:
+		return false
+	}
+
+	return secret
+}
@@ -0,0 +1,138 @@
+package confi_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jfk9w-go/confi"
+)
+
+type mockRemoteSource struct {
+	values map[string]any
+	err    error
+}
+
+func (s *mockRemoteSource) GetValues(ctx context.Context) (map[string]any, error) {
+	return s.values, s.err
+}
+
+func TestCachedSource(t *testing.T) {
+	type Config struct {
+		Host     string               `yaml:"host"`
+		Port     int                  `yaml:"port"`
+		Password confi.Secret[string] `yaml:"password"`
+		Tokens   []confi.Secret[int]  `yaml:"tokens,omitempty"`
+	}
+
+	key, err := confi.GenerateKey()
+	require.NoError(t, err)
+	cipher, err := confi.NewCipher(key)
+	require.NoError(t, err)
+
+	remote := &mockRemoteSource{values: map[string]any{
+		"host":     "remote",
+		"port":     8080,
+		"password": "secret",
+		"tokens":   []any{1234},
+	}}
+
+	path := filepath.Join(t.TempDir(), "cache", "app.yaml")
+	source := &confi.CachedSource{Source: remote, Path: path, Cipher: cipher}
+
+	var warnings []confi.Warning
+	load := func(sources ...confi.Source) (*Config, error) {
+		warnings = nil
+		config, _, err := confi.FromProvider[Config](context.Background(),
+			confi.SourceProviderFunc(func(ctx context.Context) ([]confi.Source, error) {
+				return append([]confi.Source{source}, sources...), nil
+			}),
+			confi.WithWarningHandler(func(warning confi.Warning) { warnings = append(warnings, warning) }))
+
+		return config, err
+	}
+
+	expected := &Config{Host: "remote", Port: 8080, Password: confi.NewSecret("secret"), Tokens: []confi.Secret[int]{confi.NewSecret(1234)}}
+
+	t.Run("no cache", func(t *testing.T) {
+		remote.err = errors.New("connection refused")
+		defer func() { remote.err = nil }()
+
+		_, err := load()
+		assert.ErrorContains(t, err, "connection refused")
+		assert.NoFileExists(t, path)
+	})
+
+	t.Run("write", func(t *testing.T) {
+		config, err := load()
+		require.NoError(t, err)
+		assert.Equal(t, expected, config)
+		assert.Empty(t, warnings)
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.True(t, confi.IsEncrypted(strings.TrimSpace(string(data))))
+		for _, value := range []string{"host", "remote", "8080", "secret", "1234"} {
+			assert.NotContains(t, string(data), value)
+		}
+	})
+
+	t.Run("failed load is not cached", func(t *testing.T) {
+		values := remote.values
+		remote.values = map[string]any{"host": "changed"}
+		defer func() { remote.values = values }()
+
+		_, err := load(confi.PropertySource{{Path: []string{"port"}, Value: "not a number"}})
+		require.Error(t, err)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "changed")
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		remote.err = errors.New("connection refused")
+		defer func() { remote.err = nil }()
+
+		config, err := load()
+		require.NoError(t, err)
+		assert.Equal(t, expected, config)
+		require.Len(t, warnings, 1)
+		assert.Equal(t, confi.WarnStaleCache, warnings[0].Code)
+		assert.Contains(t, warnings[0].Message, "connection refused")
+	})
+
+	t.Run("no key", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app.yaml")
+		source = &confi.CachedSource{Source: remote, Path: path}
+
+		_, err := load()
+		require.NoError(t, err)
+		require.Len(t, warnings, 1)
+		assert.Equal(t, confi.WarnCacheWrite, warnings[0].Code)
+		assert.NoFileExists(t, path)
+	})
+
+	t.Run("no key without secrets", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app.yaml")
+		source = &confi.CachedSource{Source: &mockRemoteSource{values: map[string]any{"host": "remote"}}, Path: path}
+
+		_, err := load()
+		require.NoError(t, err)
+		assert.Empty(t, warnings)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "host: remote\n", string(data))
+	})
+}
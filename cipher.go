@@ -0,0 +1,244 @@
+package confi
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	encryptedPrefix = "ENC["
+	encryptedSuffix = "]"
+	encryptedMethod = "AES256_GCM"
+)
+
+// Cipher encrypts and decrypts configuration values with AES-256-GCM.
+// Encrypted values look like ENC[AES256_GCM,data:<base64>,iv:<base64>].
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// GenerateKey returns a new random base64-encoded key suitable for NewCipher.
+func GenerateKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// NewCipher creates a Cipher from base64-encoded 256-bit key.
+func NewCipher(key string) (*Cipher, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return nil, errors.Wrap(err, "decode key")
+	}
+
+	if len(data) != 32 {
+		return nil, errors.Errorf("expected 32 bytes key, got %d", len(data))
+	}
+
+	block, err := aes.NewCipher(data)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cipher{aead: aead}, nil
+}
+
+// LoadCipher creates a Cipher from key stored in file.
+func LoadCipher(path string) (*Cipher, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCipher(string(data))
+}
+
+// IsEncrypted reports whether value is encrypted with Cipher.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix) && strings.HasSuffix(value, encryptedSuffix)
+}
+
+// Encrypt encrypts value.
+func (c *Cipher) Encrypt(value string) (string, error) {
+	iv := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+
+	data := c.aead.Seal(nil, iv, []byte(value), nil)
+	return encryptedPrefix + encryptedMethod +
+		",data:" + base64.StdEncoding.EncodeToString(data) +
+		",iv:" + base64.StdEncoding.EncodeToString(iv) +
+		encryptedSuffix, nil
+}
+
+// Decrypt decrypts value produced by Encrypt.
+func (c *Cipher) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", errors.New("value is not encrypted")
+	}
+
+	tokens := strings.Split(value[len(encryptedPrefix):len(value)-len(encryptedSuffix)], ",")
+	if tokens[0] != encryptedMethod {
+		return "", errors.Errorf("unsupported method %s", tokens[0])
+	}
+
+	var data, iv []byte
+	for _, token := range tokens[1:] {
+		key, text, _ := strings.Cut(token, ":")
+		decoded, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return "", errors.Wrapf(err, "decode %s", key)
+		}
+
+		switch key {
+		case "data":
+			data = decoded
+		case "iv":
+			iv = decoded
+		}
+	}
+
+	if len(iv) != c.aead.NonceSize() {
+		return "", errors.New("invalid iv")
+	}
+
+	plaintext, err := c.aead.Open(nil, iv, data, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "decrypt")
+	}
+
+	return string(plaintext), nil
+}
+
+// decryptValues decrypts encrypted string values. If cipher is nil, encrypted values are reported as errors.
+func decryptValues(c *Cipher, values map[string]any) (map[string]any, error) {
+	result, err := transformStrings(values, nil, func(path []string, text string) (any, error) {
+		if !IsEncrypted(text) {
+			return text, nil
+		}
+
+		if c == nil {
+			return nil, errors.Errorf("on %s: encrypted value found, but no key configured", strings.Join(path, "."))
+		}
+
+		value, err := c.Decrypt(text)
+		if err != nil {
+			return nil, errors.Wrapf(err, "on %s", strings.Join(path, "."))
+		}
+
+		return value, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(map[string]any), nil
+}
+
+// EncryptFile encrypts values of write-only properties (such as Secret fields) in YAML or JSON file in place.
+// Already encrypted values are left as is. Comments and key order of YAML files are preserved.
+func (c *Cipher) EncryptFile(path string, schema *Schema) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return errors.Wrap(err, "unmarshal")
+	}
+
+	if err := c.encryptNode(&node, schema); err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	switch format := strings.TrimPrefix(filepath.Ext(path), "."); format {
+	case "yaml", "yml":
+		encoder := yaml.NewEncoder(&b)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&node); err != nil {
+			return errors.Wrap(err, "encode")
+		}
+
+	case "json":
+		if err := JSON.Marshal(&node, &b); err != nil {
+			return errors.Wrap(err, "encode")
+		}
+
+	default:
+		return errors.Errorf("unsupported format %s", format)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, b.Bytes(), info.Mode().Perm())
+}
+
+func (c *Cipher) encryptNode(node *yaml.Node, schema *Schema) error {
+	if schema == nil {
+		return nil
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			if err := c.encryptNode(child, schema); err != nil {
+				return err
+			}
+		}
+
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content); i += 2 {
+			key, value := node.Content[i].Value, node.Content[i+1]
+			if err := c.encryptNode(value, schema.Lookup(key)); err != nil {
+				return errors.Wrapf(err, "on %s", key)
+			}
+		}
+
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			if err := c.encryptNode(item, schema.Items); err != nil {
+				return errors.Wrapf(err, "on index %d", i)
+			}
+		}
+
+	case yaml.ScalarNode:
+		if !schema.WriteOnly || node.ShortTag() == "!!null" || IsEncrypted(node.Value) {
+			return nil
+		}
+
+		value, err := c.Encrypt(node.Value)
+		if err != nil {
+			return err
+		}
+
+		node.Value = value
+		node.Tag = "!!str"
+		node.Style = 0
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package confi_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jfk9w-go/confi"
+)
+
+func TestCipher(t *testing.T) {
+	key, err := confi.GenerateKey()
+	require.NoError(t, err)
+	cipher, err := confi.NewCipher(key)
+	require.NoError(t, err)
+
+	encrypted, err := cipher.Encrypt("secret")
+	require.NoError(t, err)
+	assert.True(t, confi.IsEncrypted(encrypted))
+	assert.True(t, strings.HasPrefix(encrypted, "ENC[AES256_GCM,data:"))
+	assert.NotContains(t, encrypted, "secret")
+
+	decrypted, err := cipher.Decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", decrypted)
+
+	_, err = cipher.Decrypt(strings.Replace(encrypted, "data:", "data:AAAA", 1))
+	assert.Error(t, err)
+
+	otherKey, err := confi.GenerateKey()
+	require.NoError(t, err)
+	other, err := confi.NewCipher(otherKey)
+	require.NoError(t, err)
+	_, err = other.Decrypt(encrypted)
+	assert.Error(t, err)
+
+	_, err = confi.NewCipher("c2hvcnQ=")
+	assert.Error(t, err)
+}
+
+func TestInputSource_GetValues_Encrypted(t *testing.T) {
+	key, err := confi.GenerateKey()
+	require.NoError(t, err)
+	cipher, err := confi.NewCipher(key)
+	require.NoError(t, err)
+	encrypted, err := cipher.Encrypt("secret")
+	require.NoError(t, err)
+
+	source := confi.InputSource{
+		Input:  confi.Reader{R: bytes.NewReader([]byte(`{db: {password: "` + encrypted + `"}}`))},
+		Format: "yaml",
+		Cipher: cipher,
+	}
+
+	values, err := source.GetValues(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"db": map[string]any{"password": "secret"}}, values)
+
+	source = confi.InputSource{
+		Input:  confi.Reader{R: bytes.NewReader([]byte("db.password=" + encrypted))},
+		Format: "properties",
+	}
+
+	_, err = source.GetValues(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "on db.password: encrypted value found, but no key configured")
+}
+
+func TestCipher_EncryptFile(t *testing.T) {
+	type Config struct {
+		DB struct {
+			User     string               `yaml:"user"`
+			Password confi.Secret[string] `yaml:"password"`
+			Port     confi.Secret[int]    `yaml:"port"`
+		} `yaml:"db"`
+		Tokens []confi.Secret[string] `yaml:"tokens"`
+	}
+
+	schema, err := confi.GenerateSchema(Config{})
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	key, err := confi.GenerateKey()
+	require.NoError(t, err)
+	keyFile := filepath.Join(dir, "key")
+	require.NoError(t, os.WriteFile(keyFile, []byte(key+"\n"), 0o600))
+	cipher, err := confi.LoadCipher(keyFile)
+	require.NoError(t, err)
+
+	files := map[string]string{
+		"config.yaml": "# database\ndb:\n  user: app # login\n  password: secret\n  port: 5432\ntokens:\n  - token\n",
+		"config.json": `{"db": {"user": "app", "password": "secret", "port": 5432}, "tokens": ["token"]}`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+			require.NoError(t, cipher.EncryptFile(path, schema))
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.NotContains(t, string(data), "secret")
+			assert.NotContains(t, string(data), "5432")
+			assert.NotContains(t, string(data), "token\n")
+			assert.Contains(t, string(data), "app")
+			if name == "config.yaml" {
+				assert.Contains(t, string(data), "# database")
+				assert.Contains(t, string(data), "# login")
+			}
+
+			// encrypting twice must not change already encrypted values
+			require.NoError(t, cipher.EncryptFile(path, schema))
+			again, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, string(data), string(again))
+
+			provider := &confi.DefaultSourceProvider{
+				Args: []string{"--config.file=" + path, "--config.key.file=" + keyFile},
+			}
+
+			if name == "config.json" {
+				provider = &confi.DefaultSourceProvider{
+					EnvPrefix: "app_",
+					Env:       []string{"app_config_key=" + key, "app_config_file=" + path},
+				}
+			}
+
+			config, _, err := confi.FromProvider[Config](context.Background(), provider)
+
+			require.NoError(t, err)
+			assert.Equal(t, "app", config.DB.User)
+			assert.Equal(t, "secret", config.DB.Password.Reveal())
+			assert.Equal(t, 5432, config.DB.Port.Reveal())
+			require.Len(t, config.Tokens, 1)
+			assert.Equal(t, "token", config.Tokens[0].Reveal())
+		})
+	}
+
+	t.Run("key in command line", func(t *testing.T) {
+		_, err := (&confi.DefaultSourceProvider{Args: []string{"--config.key=" + key}}).GetSources(context.Background())
+		assert.ErrorContains(t, err, "config.key must not be passed in command line")
+	})
+}
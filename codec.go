@@ -4,6 +4,7 @@ import (
 	"encoding/gob"
 	"encoding/json"
 	"io"
+	"reflect"
 
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
@@ -12,17 +13,27 @@ import (
 type Codec struct {
 	MarshalFn   func(value any, writer io.Writer) error
 	UnmarshalFn func(reader io.Reader, value any) error
+
+	// Ordered enables passing mappings to MarshalFn as OrderedMap[any] in order to preserve key order.
+	// MarshalFn receives map[string]any otherwise.
+	Ordered bool
 }
 
-func (c Codec) Marshal(value any, writer io.Writer) error {
-	data, err := yaml.Marshal(value)
+// Marshal encodes value with MarshalFn.
+// Struct fields are named with struct tags set by WithTagNames (yaml and json by default).
+func (c Codec) Marshal(value any, writer io.Writer, opts ...Option) error {
+	node, err := newOptions(opts).tagNames.encodeNode(reflect.ValueOf(value))
 	if err != nil {
-		return errors.Wrap(err, "marshal value to yaml")
+		return errors.Wrap(err, "encode value to yaml")
 	}
 
-	values := make(map[string]any)
-	if err := yaml.Unmarshal(data, &values); err != nil {
-		return errors.Wrap(err, "unmarshal values from yaml")
+	values, err := nodeValue(node)
+	if err != nil {
+		return errors.Wrap(err, "decode values from yaml")
+	}
+
+	if !c.Ordered {
+		values = plainValue(values)
 	}
 
 	if err := c.MarshalFn(values, writer); err != nil {
@@ -32,7 +43,9 @@ func (c Codec) Marshal(value any, writer io.Writer) error {
 	return nil
 }
 
-func (c Codec) Unmarshal(reader io.Reader, value any) error {
+// Unmarshal decodes value with UnmarshalFn.
+// Struct fields are resolved with struct tags set by WithTagNames (yaml and json by default).
+func (c Codec) Unmarshal(reader io.Reader, value any, opts ...Option) error {
 	values := make(map[string]any)
 	if err := c.UnmarshalFn(reader, &values); err != nil {
 		return errors.Wrap(err, "unmarshal values")
@@ -43,7 +56,7 @@ func (c Codec) Unmarshal(reader io.Reader, value any) error {
 		return errors.Wrap(err, "marshal values to yaml")
 	}
 
-	if err := yaml.Unmarshal(data, value); err != nil {
+	if err := newOptions(opts).tagNames.unmarshalYAML(data, value); err != nil {
 		return errors.Wrap(err, "unmarshal value from yaml")
 	}
 
@@ -58,6 +71,7 @@ var JSON = Codec{
 	},
 
 	UnmarshalFn: func(reader io.Reader, value any) error { return json.NewDecoder(reader).Decode(value) },
+	Ordered:     true,
 }
 
 var YAML = Codec{
@@ -68,6 +82,7 @@ var YAML = Codec{
 	},
 
 	UnmarshalFn: func(reader io.Reader, value any) error { return yaml.NewDecoder(reader).Decode(value) },
+	Ordered:     true,
 }
 
 var Gob = Codec{
@@ -92,3 +107,50 @@ func (f Format) SchemaEnum() any {
 
 	return formats
 }
+
+func nodeValue(node *yaml.Node) (any, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+
+		return nodeValue(node.Content[0])
+
+	case yaml.AliasNode:
+		return nodeValue(node.Alias)
+
+	case yaml.MappingNode:
+		var values OrderedMap[any]
+		for i := 0; i < len(node.Content); i += 2 {
+			value, err := nodeValue(node.Content[i+1])
+			if err != nil {
+				return nil, errors.Wrapf(err, "on key %s", node.Content[i].Value)
+			}
+
+			values.Set(node.Content[i].Value, value)
+		}
+
+		return values, nil
+
+	case yaml.SequenceNode:
+		values := make([]any, len(node.Content))
+		for i, item := range node.Content {
+			value, err := nodeValue(item)
+			if err != nil {
+				return nil, errors.Wrapf(err, "on index %d", i)
+			}
+
+			values[i] = value
+		}
+
+		return values, nil
+	}
+
+	var value any
+	if err := node.Decode(&value); err != nil {
+		return nil, errors.Wrap(err, "decode scalar")
+	}
+
+	return value, nil
+}
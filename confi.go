@@ -5,48 +5,164 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
 )
 
-func Get[T any](ctx context.Context, appName string) (*T, *Schema, error) {
+// Option configures Get and FromProvider.
+type Option func(*options)
+
+type options struct {
+	lookupEnv       func(name string) (string, bool)
+	references      bool
+	secretResolvers map[string]SecretResolver
+	secretTimeout   time.Duration
+	secretPolicy    SecretPolicy
+	warningHandler  func(Warning)
+	tagNames        tagNames
+}
+
+func newOptions(opts []Option) options {
+	options := options{secretTimeout: DefaultSecretTimeout}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return options
+}
+
+// WithEnvExpansion enables expansion of ${VAR}, ${VAR:-default} and ${VAR:?error} expressions
+// in string values from all sources. "$$" is replaced with "$".
+// Variables are resolved with lookup or os.LookupEnv if lookup is nil.
+func WithEnvExpansion(lookup func(name string) (string, bool)) Option {
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+
+	return func(options *options) {
+		options.lookupEnv = lookup
+	}
+}
+
+// WithReferences enables ${ref:path} expressions in string values from all sources.
+// References are resolved against values merged from all sources (or schema defaults if the property is not set).
+// A value consisting of a single reference takes the referenced value as is, keeping its type,
+// otherwise the referenced value must be a scalar and is interpolated into the string.
+func WithReferences() Option {
+	return func(options *options) {
+		options.references = true
+	}
+}
+
+// WithSecretResolver registers resolver for string values which are URIs with the scheme
+// (e.g. "secret" for secret://vault/kv/app#password).
+// See FileSecretResolver and ExecSecretResolver for built-in resolvers.
+func WithSecretResolver(scheme string, resolver SecretResolver) Option {
+	return func(options *options) {
+		if options.secretResolvers == nil {
+			options.secretResolvers = make(map[string]SecretResolver)
+		}
+
+		options.secretResolvers[scheme] = resolver
+	}
+}
+
+// WithSecretTimeout sets timeout of a single SecretResolver call (DefaultSecretTimeout by default).
+func WithSecretTimeout(timeout time.Duration) Option {
+	return func(options *options) {
+		options.secretTimeout = timeout
+	}
+}
+
+func Get[T any](ctx context.Context, appName string, opts ...Option) (*T, *Schema, error) {
+	schema, err := GenerateSchema(*new(T), opts...)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "generate schema")
+	}
+
 	replacer := strings.NewReplacer(`-`, `_`, `.`, `_`)
 	provider := &DefaultSourceProvider{
-		EnvPrefix: replacer.Replace(appName) + "_",
-		Env:       os.Environ(),
-		Args:      os.Args[1:],
-		Stdin:     os.Stdin,
+		EnvPrefix:   replacer.Replace(appName) + "_",
+		Env:         os.Environ(),
+		Args:        os.Args[1:],
+		Stdin:       os.Stdin,
+		Schema:      schema,
+		SearchPaths: DefaultSearchPaths(appName),
+
+		CredentialsDirectory: os.Getenv(CredentialsDirectoryEnv),
 	}
 
-	return FromProvider[T](ctx, provider)
+	return FromProvider[T](ctx, provider, opts...)
 }
 
-func FromProvider[T any](ctx context.Context, provider SourceProvider) (*T, *Schema, error) {
+func FromProvider[T any](ctx context.Context, provider SourceProvider, opts ...Option) (*T, *Schema, error) {
+	options := newOptions(opts)
+	ctx = withWarningHandler(ctx, options.warningHandler)
+	ctx = withSecretPolicy(ctx, options.secretPolicy)
+
 	sources, err := provider.GetSources(ctx)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "get sources")
 	}
 
+	return fromSources[T](ctx, sources, options)
+}
+
+func fromSources[T any](ctx context.Context, sources []Source, options options) (*T, *Schema, error) {
 	var config T
 
-	schema, err := GenerateSchema(config)
+	schema, err := schemaGenerator{tags: options.tagNames}.generate(config)
 	if err != nil {
 		return nil, nil, errors.Wrapf(err, "generate schema")
 	}
 
-	for _, source := range sources {
-		values, err := source.GetValues(ctx)
+	allValues := make([]map[string]any, len(sources))
+	for i, source := range sources {
+		var raw map[string]any
+		if input, ok := source.(InputSource); ok {
+			// secrets are checked before decryption, since encrypted secrets are safe to store in any file
+			allValues[i], raw, err = input.getValues(ctx)
+		} else {
+			allValues[i], err = source.GetValues(ctx)
+		}
+
 		if err != nil {
 			return nil, nil, errors.Wrapf(err, "get values from %s", source)
 		}
 
-		data, err := yaml.Marshal(SpecifyType(values))
+		if err := options.secretPolicy.checkSource(ctx, source, raw, schema); err != nil {
+			return nil, nil, errors.Wrap(err, "check secrets")
+		}
+	}
+
+	secrets := newSecretStore(options)
+	if options.lookupEnv != nil || options.references {
+		resolver := newResolver(allValues, schema, options)
+		resolver.resolveSecret = func(path []string, text string) (string, error) {
+			return secrets.resolveString(ctx, path, text)
+		}
+
+		for i, source := range sources {
+			allValues[i], err = resolver.resolve(allValues[i])
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "resolve values from %s", source)
+			}
+		}
+	}
+
+	if err := secrets.resolveAll(ctx, allValues); err != nil {
+		return nil, nil, errors.Wrap(err, "resolve secrets")
+	}
+
+	for i, source := range sources {
+		data, err := yaml.Marshal(SpecifyType(allValues[i]))
 		if err != nil {
 			return nil, nil, errors.Wrapf(err, "marshal values from %s to yaml", source)
 		}
 
-		if err := yaml.Unmarshal(data, &config); err != nil {
+		if err := options.tagNames.unmarshalYAML(data, &config); err != nil {
 			return nil, nil, errors.Wrapf(err, "unmarshal values from %s from yaml", source)
 		}
 	}
@@ -55,6 +171,12 @@ func FromProvider[T any](ctx context.Context, provider SourceProvider) (*T, *Sch
 		return nil, nil, errors.Wrap(err, "apply defaults")
 	}
 
+	for _, source := range sources {
+		if committer, ok := source.(loadCommitter); ok {
+			committer.commitLoad(ctx, schema)
+		}
+	}
+
 	return &config, schema, nil
 }
 
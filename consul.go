@@ -0,0 +1,188 @@
+package confi
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultConsulAddress is the default address of Consul HTTP API.
+	DefaultConsulAddress = "http://127.0.0.1:8500"
+	// DefaultConsulWait is the default wait time of Consul blocking queries.
+	DefaultConsulWait = 5 * time.Minute
+)
+
+// ConsulSource reads properties from Consul KV tree under Prefix.
+// Keys relative to Prefix split by "/" are property paths, and values are property values.
+// Watch uses blocking queries, so the source must be used by pointer.
+type ConsulSource struct {
+	// Address of Consul HTTP API (DefaultConsulAddress by default).
+	Address string
+
+	// Prefix of the keys (e.g. "config/app").
+	Prefix string
+
+	// Token is sent in X-Consul-Token header, if set.
+	Token string
+
+	// Datacenter to query. The datacenter of the agent is used by default.
+	Datacenter string
+
+	// Client is used for requests (http.DefaultClient by default).
+	Client *http.Client
+
+	// Wait is the maximum duration of a blocking query in Watch (DefaultConsulWait by default).
+	Wait time.Duration
+
+	mu    sync.Mutex
+	index uint64
+}
+
+type consulKVPair struct {
+	Key   string
+	Value *string
+}
+
+func (s *ConsulSource) GetValues(ctx context.Context) (map[string]any, error) {
+	pairs, index, err := s.list(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.index = index
+	s.mu.Unlock()
+
+	prefix := s.prefix()
+	props := make(PropertySource, 0, len(pairs))
+	for _, pair := range pairs {
+		key, ok := strings.CutPrefix(pair.Key, prefix)
+		if !ok || pair.Value == nil || key == "" || strings.HasSuffix(key, "/") {
+			// folders are skipped
+			continue
+		}
+
+		value, err := base64.StdEncoding.DecodeString(*pair.Value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode value of %s", pair.Key)
+		}
+
+		props = append(props, Property{
+			Path:  strings.Split(key, "/"),
+			Value: string(value),
+		})
+	}
+
+	return props.GetValues(ctx)
+}
+
+// Watch performs blocking queries until the index of the prefix changes since the last GetValues call.
+// Failed queries are retried with exponential backoff (see DefaultWatchBackoff),
+// so that temporary unavailability does not stop watching.
+func (s *ConsulSource) Watch(ctx context.Context) error {
+	s.mu.Lock()
+	index := s.index
+	s.mu.Unlock()
+
+	var retry backoff
+	for {
+		_, newIndex, err := s.list(ctx, index)
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+
+		case err != nil, newIndex == 0:
+			if err := retry.wait(ctx); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		retry.reset()
+		switch {
+		case index == 0:
+			// GetValues has not been called yet, so start watching from the current index
+			index = newIndex
+
+		case newIndex != index:
+			// index may also go backwards (e.g. after snapshot restore), which is a change as well
+			return nil
+		}
+	}
+}
+
+func (s *ConsulSource) String() string {
+	return "consul " + s.Prefix
+}
+
+// prefix returns Prefix without leading slash and with trailing slash.
+func (s *ConsulSource) prefix() string {
+	if prefix := strings.Trim(s.Prefix, "/"); prefix != "" {
+		return prefix + "/"
+	}
+
+	return ""
+}
+
+// list reads all keys under prefix. If index is not zero, it performs a blocking query.
+func (s *ConsulSource) list(ctx context.Context, index uint64) ([]consulKVPair, uint64, error) {
+	address := s.Address
+	if address == "" {
+		address = DefaultConsulAddress
+	}
+
+	query := url.Values{"recurse": {"true"}}
+	if s.Datacenter != "" {
+		query.Set("dc", s.Datacenter)
+	}
+
+	if index > 0 {
+		wait := s.Wait
+		if wait <= 0 {
+			wait = DefaultConsulWait
+		}
+
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", strconv.FormatInt(wait.Milliseconds(), 10)+"ms")
+	}
+
+	header := make(http.Header)
+	if s.Token != "" {
+		header.Set("X-Consul-Token", s.Token)
+	}
+
+	endpoint := strings.TrimSuffix(address, "/") + "/v1/kv/" + s.prefix() + "?" + query.Encode()
+	resp, err := sendRequest(ctx, s.Client, http.MethodGet, endpoint, nil, header)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer CloseQuietly(resp.Body)
+
+	newIndex, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		// no keys under the prefix
+		return nil, newIndex, nil
+	default:
+		return nil, 0, errors.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var pairs []consulKVPair
+	if err := json.NewDecoder(resp.Body).Decode(&pairs); err != nil {
+		return nil, 0, errors.Wrap(err, "decode response")
+	}
+
+	return pairs, newIndex, nil
+}
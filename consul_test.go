@@ -0,0 +1,198 @@
+package confi_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jfk9w-go/confi"
+)
+
+// fakeConsul implements a subset of Consul KV HTTP API.
+type fakeConsul struct {
+	mu       sync.Mutex
+	kv       map[string]string
+	index    uint64
+	changed  chan struct{}
+	token    string
+	failures int
+}
+
+func newFakeConsul(token string, kv map[string]string) *fakeConsul {
+	return &fakeConsul{kv: kv, index: 10, changed: make(chan struct{}), token: token}
+}
+
+func (c *fakeConsul) put(key, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.kv[key] = value
+	c.index++
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+func (c *fakeConsul) fail(requests int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures = requests
+}
+
+func (c *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	failed := c.failures > 0
+	if failed {
+		c.failures--
+	}
+
+	c.mu.Unlock()
+	if failed {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	if r.Header.Get("X-Consul-Token") != c.token {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	prefix, ok := strings.CutPrefix(r.URL.Path, "/v1/kv/")
+	if !ok || r.URL.Query().Get("recurse") != "true" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	if index, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64); index >= c.index {
+		wait, err := time.ParseDuration(r.URL.Query().Get("wait"))
+		if err != nil {
+			c.mu.Unlock()
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		changed := c.changed
+		c.mu.Unlock()
+		select {
+		case <-changed:
+		case <-time.After(wait):
+		case <-r.Context().Done():
+		}
+
+		c.mu.Lock()
+	}
+
+	defer c.mu.Unlock()
+
+	type pair struct {
+		Key         string
+		Value       *string
+		ModifyIndex uint64
+	}
+
+	var pairs []pair
+	for key, value := range c.kv {
+		if strings.HasPrefix(key, prefix) {
+			var encoded *string
+			if !strings.HasSuffix(key, "/") {
+				text := base64.StdEncoding.EncodeToString([]byte(value))
+				encoded = &text
+			}
+
+			pairs = append(pairs, pair{Key: key, Value: encoded, ModifyIndex: c.index})
+		}
+	}
+
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(c.index, 10))
+	if len(pairs) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	_ = json.NewEncoder(w).Encode(pairs)
+}
+
+func TestConsulSource(t *testing.T) {
+	consul := newFakeConsul("token", map[string]string{
+		"config/app/":              "",
+		"config/app/db/":           "",
+		"config/app/db/host":       "localhost",
+		"config/app/db/port":       "5432",
+		"config/app/log.level":     "debug",
+		"config/application/other": "other",
+	})
+
+	server := httptest.NewServer(consul)
+	defer server.Close()
+
+	source := &confi.ConsulSource{Address: server.URL, Prefix: "/config/app", Token: "token", Wait: time.Second}
+	values, err := source.GetValues(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"db":        map[string]any{"host": "localhost", "port": "5432"},
+		"log.level": "debug",
+	}, values)
+
+	values, err = (&confi.ConsulSource{Address: server.URL, Prefix: "config/missing", Token: "token"}).GetValues(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, values)
+
+	_, err = (&confi.ConsulSource{Address: server.URL, Prefix: "config/app"}).GetValues(context.Background())
+	assert.EqualError(t, err, "unexpected status 403 Forbidden")
+
+	t.Run("watch", func(t *testing.T) {
+		type Config struct {
+			DB struct {
+				Host string `yaml:"host"`
+				Port int    `yaml:"port"`
+			} `yaml:"db"`
+		}
+
+		provider := confi.SourceProviderFunc(func(ctx context.Context) ([]confi.Source, error) {
+			return []confi.Source{source}, nil
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		hosts := make(chan string)
+		done := make(chan error)
+		go func() {
+			done <- confi.Watch[Config](ctx, provider, func(config *Config, _ *confi.Schema, err error) {
+				if assert.NoError(t, err) {
+					hosts <- config.DB.Host
+				}
+			})
+		}()
+
+		assert.Equal(t, "localhost", <-hosts)
+		consul.put("config/app/db/host", "remote")
+		assert.Equal(t, "remote", <-hosts)
+
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+	})
+	t.Run("watch retries", func(t *testing.T) {
+		_, err := source.GetValues(context.Background())
+		require.NoError(t, err)
+
+		consul.fail(2)
+		consul.put("config/app/db/host", "retried")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		assert.NoError(t, source.Watch(ctx))
+	})
+}
@@ -0,0 +1,23 @@
+package confi
+
+import (
+	"context"
+)
+
+// CredentialsDirectoryEnv is the environment variable set by systemd for services with credentials.
+const CredentialsDirectoryEnv = "CREDENTIALS_DIRECTORY"
+
+// CredentialsSource reads systemd credentials (see LoadCredential= and SetCredential= in systemd.exec(5)).
+// Each file in Dir is a property: file name split by "." is the property path (db.password → db.password),
+// and file contents with a single trailing newline trimmed is the value (see DirectorySource).
+type CredentialsSource struct {
+	Dir string
+}
+
+func (s CredentialsSource) GetValues(ctx context.Context) (map[string]any, error) {
+	return (&DirectorySource{Dir: s.Dir, Separator: "."}).GetValues(ctx)
+}
+
+func (s CredentialsSource) String() string {
+	return "credentials " + s.Dir
+}
@@ -0,0 +1,64 @@
+package confi_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jfk9w-go/confi"
+)
+
+func TestCredentialsSource(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"db.password": "secret\n",
+		"db.user":     "creds",
+		"token":       "token",
+		".hidden":     "hidden",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "nested"), 0o700))
+
+	values, err := confi.CredentialsSource{Dir: dir}.GetValues(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"db":    map[string]any{"password": "secret", "user": "creds"},
+		"token": "token",
+	}, values)
+
+	_, err = confi.CredentialsSource{Dir: filepath.Join(dir, "missing")}.GetValues(context.Background())
+	assert.Error(t, err)
+
+	t.Run("priority", func(t *testing.T) {
+		type Config struct {
+			DB struct {
+				User     string               `yaml:"user"`
+				Password confi.Secret[string] `yaml:"password"`
+				Host     string               `yaml:"host"`
+			} `yaml:"db"`
+			Token string `yaml:"token"`
+		}
+
+		file := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(file, []byte("db: {user: file, password: file, host: file}"), 0o600))
+
+		config, _, err := confi.FromProvider[Config](context.Background(), &confi.DefaultSourceProvider{
+			EnvPrefix:            "app_",
+			Env:                  []string{"app_token=env", "app_config_file=" + file},
+			Args:                 []string{"--db.user=args"},
+			CredentialsDirectory: dir,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "args", config.DB.User)
+		assert.Equal(t, "secret", config.DB.Password.Reveal())
+		assert.Equal(t, "file", config.DB.Host)
+		assert.Equal(t, "token", config.Token)
+	})
+}
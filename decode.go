@@ -0,0 +1,224 @@
+package confi
+
+import (
+	"encoding"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// unmarshalYAML works like yaml.Unmarshal, but struct fields are resolved with t.
+func (t tagNames) unmarshalYAML(data []byte, value any) error {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+
+	target := reflect.ValueOf(value)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return errors.Errorf("expected non-nil pointer, got %T", value)
+	}
+
+	return t.decodeNode(&node, target.Elem())
+}
+
+func (t tagNames) decodeNode(node *yaml.Node, value reflect.Value) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil
+		}
+
+		return t.decodeNode(node.Content[0], value)
+
+	case yaml.AliasNode:
+		return t.decodeNode(node.Alias, value)
+	}
+
+	if node.ShortTag() == "!!null" {
+		switch value.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+			value.Set(reflect.Zero(value.Type()))
+		}
+
+		return nil
+	}
+
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+
+		return t.decodeNode(node, value.Elem())
+	}
+
+	if value.CanAddr() {
+		if secret, ok := value.Addr().Interface().(secretDecoder); ok {
+			return secret.decodeSecret(t, node)
+		}
+	}
+
+	if !hasUnmarshaler(value.Type()) {
+		switch {
+		case value.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+			return t.decodeStruct(node, value)
+
+		case value.Kind() == reflect.Map && node.Kind == yaml.MappingNode:
+			return t.decodeMap(node, value)
+
+		case value.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+			slice := reflect.MakeSlice(value.Type(), len(node.Content), len(node.Content))
+			for i, item := range node.Content {
+				if err := t.decodeNode(item, slice.Index(i)); err != nil {
+					return errors.Wrapf(err, "on index %d", i)
+				}
+			}
+
+			value.Set(slice)
+			return nil
+
+		case value.Kind() == reflect.Array && node.Kind == yaml.SequenceNode:
+			array := reflect.New(value.Type()).Elem()
+			for i, item := range node.Content {
+				if i >= array.Len() {
+					break
+				}
+
+				if err := t.decodeNode(item, array.Index(i)); err != nil {
+					return errors.Wrapf(err, "on index %d", i)
+				}
+			}
+
+			value.Set(array)
+			return nil
+		}
+	}
+
+	target := reflect.New(value.Type())
+	target.Elem().Set(value)
+	if err := node.Decode(target.Interface()); err != nil {
+		return err
+	}
+
+	value.Set(target.Elem())
+	return nil
+}
+
+func hasUnmarshaler(valueType reflect.Type) bool {
+	if valueType == nodeType {
+		return true
+	}
+
+	valueType = reflect.PointerTo(valueType)
+	return valueType.Implements(yamlUnmarshalerType) || valueType.Implements(textUnmarshalerType)
+}
+
+func (t tagNames) decodeMap(node *yaml.Node, value reflect.Value) error {
+	if value.IsNil() {
+		value.Set(reflect.MakeMap(value.Type()))
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		key := reflect.New(value.Type().Key()).Elem()
+		if err := t.decodeNode(node.Content[i], key); err != nil {
+			return errors.Wrapf(err, "on key %s", node.Content[i].Value)
+		}
+
+		elem := reflect.New(value.Type().Elem()).Elem()
+		if err := t.decodeNode(node.Content[i+1], elem); err != nil {
+			return errors.Wrapf(err, "on key %s", node.Content[i].Value)
+		}
+
+		value.SetMapIndex(key, elem)
+	}
+
+	return nil
+}
+
+func (t tagNames) decodeStruct(node *yaml.Node, value reflect.Value) error {
+	used := make(map[int]bool)
+	if err := t.decodeFields(node, value, used); err != nil {
+		return err
+	}
+
+	for fieldNum := 0; fieldNum < value.NumField(); fieldNum++ {
+		field := value.Type().Field(fieldNum)
+		options := t.fieldOptions(field)
+		if options.skip || !options.inline || field.Type.Kind() != reflect.Map {
+			continue
+		}
+
+		remaining := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for i := 0; i < len(node.Content); i += 2 {
+			if !used[i] {
+				remaining.Content = append(remaining.Content, node.Content[i], node.Content[i+1])
+			}
+		}
+
+		if err := t.decodeMap(remaining, value.Field(fieldNum)); err != nil {
+			return errors.Wrapf(err, "on embedded field %s", field.Name)
+		}
+	}
+
+	return nil
+}
+
+func (t tagNames) decodeFields(node *yaml.Node, value reflect.Value, used map[int]bool) error {
+	for fieldNum := 0; fieldNum < value.NumField(); fieldNum++ {
+		field := value.Type().Field(fieldNum)
+		options := t.fieldOptions(field)
+		if options.skip {
+			continue
+		}
+
+		fieldValue := value.Field(fieldNum)
+		if options.inline {
+			if indirectType(field.Type).Kind() != reflect.Struct {
+				continue
+			}
+
+			if fieldValue.Kind() == reflect.Ptr {
+				if fieldValue.IsNil() {
+					fieldValue.Set(reflect.New(field.Type.Elem()))
+				}
+
+				fieldValue = fieldValue.Elem()
+			}
+
+			if err := t.decodeFields(node, fieldValue, used); err != nil {
+				return errors.Wrapf(err, "on embedded field %s", field.Name)
+			}
+
+			continue
+		}
+
+		index := findKey(node, options.name)
+		if index < 0 {
+			continue
+		}
+
+		used[index] = true
+		if err := t.decodeNode(node.Content[index+1], fieldValue); err != nil {
+			return errors.Wrapf(err, "on field %s", options.name)
+		}
+	}
+
+	return nil
+}
+
+// findKey returns index of the key node matching name in mapping node.
+func findKey(node *yaml.Node, name string) int {
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value == name {
+			return i
+		}
+	}
+
+	return -1
+}
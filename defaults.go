@@ -0,0 +1,160 @@
+package confi
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultsPass holds state of Schema.ApplyDefaults call.
+type defaultsPass struct {
+	templates bool
+	root      reflect.Value
+	schema    *Schema
+	resolver  *resolver
+}
+
+func newDefaultsPass(root reflect.Value, schema *Schema) *defaultsPass {
+	pass := &defaultsPass{root: root, schema: schema}
+	pass.resolver = &resolver{
+		find:      pass.lookup,
+		schema:    schema,
+		lookupEnv: os.LookupEnv,
+		refs:      true,
+		final:     true,
+	}
+
+	return pass
+}
+
+func (p *defaultsPass) setTemplate(value reflect.Value, template string) error {
+	result, err := p.resolver.resolveTemplate(template)
+	if err != nil {
+		return errors.Wrapf(err, "expand %s", template)
+	}
+
+	if text, ok := result.(string); ok && value.Kind() == reflect.String {
+		value.SetString(text)
+		return nil
+	}
+
+	var node *yaml.Node
+	if text, ok := result.(string); ok {
+		node = new(yaml.Node)
+		if err := yaml.Unmarshal([]byte(text), node); err != nil {
+			return errors.Wrapf(err, "unmarshal %s", text)
+		}
+	} else if node, err = p.schema.tags.encodeNode(reflect.ValueOf(result)); err != nil {
+		return errors.Wrap(err, "encode referenced value")
+	}
+
+	target := reflect.New(value.Type())
+	if err := p.schema.tags.decodeNode(node, target.Elem()); err != nil {
+		return errors.Wrapf(err, "decode %s", template)
+	}
+
+	value.Set(target.Elem())
+	return nil
+}
+
+// lookup returns the value stored at path for the resolver.
+// Values which are not set yet and have a templated default are reported as missing, so that the template is evaluated.
+func (p *defaultsPass) lookup(path []string) (any, bool) {
+	value, schema, err := p.find(path)
+	if err != nil || value.IsZero() && schema.defaultTemplate != "" {
+		return nil, false
+	}
+
+	if secret, ok := value.Interface().(secret); ok {
+		return secret.secretValue(), true
+	}
+
+	return value.Interface(), true
+}
+
+func (p *defaultsPass) find(path []string) (reflect.Value, *Schema, error) {
+	value, schema := p.root, p.schema
+	for i, name := range path {
+		for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+			if value.IsNil() {
+				return reflect.Value{}, nil, errors.Errorf("%s is nil", strings.Join(path[:i], "."))
+			}
+
+			value = value.Elem()
+		}
+
+		switch value.Kind() {
+		case reflect.Struct:
+			field, ok := p.schema.tags.structField(value, name)
+			if !ok {
+				return reflect.Value{}, nil, errors.Errorf("%s not found", strings.Join(path[:i+1], "."))
+			}
+
+			property, _ := schema.Properties.Get(name)
+			value, schema = field, &property
+
+		case reflect.Map:
+			key := reflect.New(value.Type().Key())
+			if err := p.schema.tags.unmarshalYAML([]byte(name), key.Interface()); err != nil {
+				return reflect.Value{}, nil, errors.Wrapf(err, "parse key %s", strings.Join(path[:i+1], "."))
+			}
+
+			value = value.MapIndex(key.Elem())
+			if !value.IsValid() {
+				return reflect.Value{}, nil, errors.Errorf("%s not found", strings.Join(path[:i+1], "."))
+			}
+
+			schema, _ = schema.AdditionalProperties.(*Schema)
+
+		case reflect.Slice, reflect.Array:
+			index, err := strconv.Atoi(name)
+			if err != nil || index < 0 || index >= value.Len() {
+				return reflect.Value{}, nil, errors.Errorf("%s not found", strings.Join(path[:i+1], "."))
+			}
+
+			value, schema = value.Index(index), schema.Items
+
+		default:
+			return reflect.Value{}, nil, errors.Errorf("%s is not a container", strings.Join(path[:i], "."))
+		}
+
+		if schema == nil {
+			schema = new(Schema)
+		}
+	}
+
+	return value, schema, nil
+}
+
+func (t tagNames) structField(value reflect.Value, name string) (reflect.Value, bool) {
+	for fieldNum := 0; fieldNum < value.NumField(); fieldNum++ {
+		options := t.fieldOptions(value.Type().Field(fieldNum))
+		if options.skip {
+			continue
+		}
+
+		field := value.Field(fieldNum)
+		if options.inline {
+			field = reflect.Indirect(field)
+			if field.Kind() != reflect.Struct {
+				continue
+			}
+
+			if field, ok := t.structField(field, name); ok {
+				return field, true
+			}
+
+			continue
+		}
+
+		if options.name == name {
+			return field, true
+		}
+	}
+
+	return reflect.Value{}, false
+}
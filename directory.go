@@ -0,0 +1,155 @@
+package confi
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DirectorySource reads a directory where each file is a property, like Kubernetes ConfigMap or Secret volumes.
+// File name split by Separator is the property path, and file contents with a single trailing newline
+// trimmed is the value. Hidden entries (including ..data symlink and ..<timestamp> directories maintained by Kubernetes)
+// are ignored, and subdirectories are ignored as well.
+// DirectorySource must be used by pointer, since Watch tracks changes since the last GetValues call.
+type DirectorySource struct {
+	Dir string
+
+	// Separator splits file names into property paths ("." by default).
+	Separator string
+
+	// PollInterval is the interval of checking the directory for changes in Watch (DefaultPollInterval by default).
+	PollInterval time.Duration
+
+	mu          sync.Mutex
+	lastVersion string
+}
+
+func (s *DirectorySource) GetValues(ctx context.Context) (map[string]any, error) {
+	// version is saved before reading files, so that changes made while reading are detected by Watch
+	s.mu.Lock()
+	s.lastVersion = s.version()
+	s.mu.Unlock()
+
+	separator := s.Separator
+	if separator == "" {
+		separator = "."
+	}
+
+	files, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+
+	props := make(PropertySource, 0, len(files))
+	for _, name := range files {
+		data, err := os.ReadFile(filepath.Join(s.Dir, name))
+		if err != nil {
+			return nil, errors.Wrapf(err, "read %s", name)
+		}
+
+		props = append(props, Property{
+			Path:  strings.Split(name, separator),
+			Value: trimNewline(string(data)),
+		})
+	}
+
+	return props.GetValues(ctx)
+}
+
+// Watch polls the directory until any of the files or the ..data symlink changes since the last GetValues call.
+func (s *DirectorySource) Watch(ctx context.Context) error {
+	interval := s.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	s.mu.Lock()
+	initial := s.lastVersion
+	s.mu.Unlock()
+	if initial == "" {
+		initial = s.version()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if s.version() != initial {
+				return nil
+			}
+		}
+	}
+}
+
+func (s *DirectorySource) String() string {
+	return "directory " + s.Dir
+}
+
+// files returns names of regular files (or symlinks to them) in the directory.
+func (s *DirectorySource) files() ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "read directory")
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		info, err := os.Stat(filepath.Join(s.Dir, entry.Name()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "stat %s", entry.Name())
+		}
+
+		if info.IsDir() {
+			continue
+		}
+
+		files = append(files, entry.Name())
+	}
+
+	return files, nil
+}
+
+// version describes current state of the directory.
+// Errors are made part of the state so that the directory appearing or disappearing is detected as a change.
+func (s *DirectorySource) version() string {
+	var b strings.Builder
+	if target, err := os.Readlink(filepath.Join(s.Dir, "..data")); err == nil {
+		b.WriteString(target)
+		b.WriteByte('\n')
+	}
+
+	files, err := s.files()
+	if err != nil {
+		return err.Error()
+	}
+
+	for _, name := range files {
+		info, err := os.Stat(filepath.Join(s.Dir, name))
+		if err != nil {
+			return err.Error()
+		}
+
+		b.WriteString(name)
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatInt(info.Size(), 10))
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatInt(info.ModTime().UnixNano(), 10))
+		b.WriteByte('\n')
+	}
+
+	return b.String()
+}
@@ -0,0 +1,97 @@
+package confi_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jfk9w-go/confi"
+)
+
+// writeConfigMap writes files in the layout of Kubernetes ConfigMap volume.
+func writeConfigMap(t *testing.T, dir, version string, files map[string]string) {
+	data := filepath.Join(dir, version)
+	require.NoError(t, os.MkdirAll(data, 0o755))
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(data, name), []byte(content), 0o644))
+		link := filepath.Join(dir, name)
+		if _, err := os.Lstat(link); os.IsNotExist(err) {
+			require.NoError(t, os.Symlink(filepath.Join("..data", name), link))
+		}
+	}
+
+	tmp := filepath.Join(dir, "..data_tmp")
+	require.NoError(t, os.Symlink(version, tmp))
+	require.NoError(t, os.Rename(tmp, filepath.Join(dir, "..data")))
+}
+
+func TestDirectorySource(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigMap(t, dir, "..2024_01_01", map[string]string{
+		"db.host":     "localhost\n",
+		"db.password": "secret",
+		"log_level":   "info",
+	})
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "nested"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".hidden"), []byte("hidden"), 0o644))
+
+	values, err := (&confi.DirectorySource{Dir: dir}).GetValues(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"db":        map[string]any{"host": "localhost", "password": "secret"},
+		"log_level": "info",
+	}, values)
+
+	values, err = (&confi.DirectorySource{Dir: dir, Separator: "_"}).GetValues(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"db.host":     "localhost",
+		"db.password": "secret",
+		"log":         map[string]any{"level": "info"},
+	}, values)
+
+	_, err = (&confi.DirectorySource{Dir: filepath.Join(dir, "missing")}).GetValues(context.Background())
+	assert.Error(t, err)
+}
+
+func TestDirectorySource_Watch(t *testing.T) {
+	type Config struct {
+		DB struct {
+			Host string `yaml:"host"`
+		} `yaml:"db"`
+	}
+
+	dir := t.TempDir()
+	writeConfigMap(t, dir, "..2024_01_01", map[string]string{"db.host": "first"})
+
+	source := &confi.DirectorySource{Dir: dir, PollInterval: 10 * time.Millisecond}
+	provider := confi.SourceProviderFunc(func(ctx context.Context) ([]confi.Source, error) {
+		return []confi.Source{source}, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	hosts := make(chan string)
+	done := make(chan error)
+	go func() {
+		done <- confi.Watch[Config](ctx, provider, func(config *Config, _ *confi.Schema, err error) {
+			if assert.NoError(t, err) {
+				hosts <- config.DB.Host
+			}
+		})
+	}()
+
+	assert.Equal(t, "first", <-hosts)
+	writeConfigMap(t, dir, "..2024_01_02", map[string]string{"db.host": "second"})
+	assert.Equal(t, "second", <-hosts)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
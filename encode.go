@@ -0,0 +1,173 @@
+package confi
+
+import (
+	"encoding"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	yamlMarshalerType = reflect.TypeOf((*yaml.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// encodeNode works like yaml.Node.Encode, but struct fields are resolved with t.
+func (t tagNames) encodeNode(value reflect.Value) (*yaml.Node, error) {
+	if value.IsValid() && value.CanInterface() {
+		switch node := value.Interface().(type) {
+		case *yaml.Node:
+			if node != nil {
+				return node, nil
+			}
+
+		case yaml.Node:
+			return &node, nil
+		}
+	}
+
+	for value.Kind() == reflect.Interface || value.Kind() == reflect.Ptr && !hasMarshaler(value.Type()) {
+		if value.IsNil() {
+			break
+		}
+
+		value = value.Elem()
+	}
+
+	if !value.IsValid() || hasMarshaler(value.Type()) {
+		return encodeScalar(value)
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		if err := t.encodeFields(node, value); err != nil {
+			return nil, err
+		}
+
+		return node, nil
+
+	case reflect.Map:
+		if value.IsNil() {
+			return encodeScalar(value)
+		}
+
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		if err := t.encodeMap(node, value); err != nil {
+			return nil, err
+		}
+
+		return node, nil
+
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && (value.IsNil() || value.Type().Elem().Kind() == reflect.Uint8) {
+			return encodeScalar(value)
+		}
+
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for i := 0; i < value.Len(); i++ {
+			item, err := t.encodeNode(value.Index(i))
+			if err != nil {
+				return nil, errors.Wrapf(err, "on index %d", i)
+			}
+
+			node.Content = append(node.Content, item)
+		}
+
+		return node, nil
+	}
+
+	return encodeScalar(value)
+}
+
+func (t tagNames) encodeFields(node *yaml.Node, value reflect.Value) error {
+	for fieldNum := 0; fieldNum < value.NumField(); fieldNum++ {
+		field := value.Type().Field(fieldNum)
+		options := t.fieldOptions(field)
+		if options.skip {
+			continue
+		}
+
+		fieldValue := value.Field(fieldNum)
+		if options.inline {
+			fieldValue = reflect.Indirect(fieldValue)
+			switch fieldValue.Kind() {
+			case reflect.Struct:
+				if err := t.encodeFields(node, fieldValue); err != nil {
+					return errors.Wrapf(err, "on embedded field %s", field.Name)
+				}
+
+			case reflect.Map:
+				if err := t.encodeMap(node, fieldValue); err != nil {
+					return errors.Wrapf(err, "on embedded field %s", field.Name)
+				}
+			}
+
+			continue
+		}
+
+		if options.omitempty && fieldValue.IsZero() {
+			continue
+		}
+
+		item, err := t.encodeNode(fieldValue)
+		if err != nil {
+			return errors.Wrapf(err, "on field %s", options.name)
+		}
+
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: options.name}, item)
+	}
+
+	return nil
+}
+
+func (t tagNames) encodeMap(node *yaml.Node, value reflect.Value) error {
+	type entry struct {
+		key, value *yaml.Node
+	}
+
+	entries := make([]entry, 0, value.Len())
+	for iter := value.MapRange(); iter.Next(); {
+		key, err := encodeScalar(iter.Key())
+		if err != nil {
+			return errors.Wrapf(err, "on key %v", iter.Key().Interface())
+		}
+
+		item, err := t.encodeNode(iter.Value())
+		if err != nil {
+			return errors.Wrapf(err, "on key %v", iter.Key().Interface())
+		}
+
+		entries = append(entries, entry{key, item})
+	}
+
+	// keys are sorted like yaml.v3 does for maps, so that output is stable
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key.Value < entries[j].key.Value })
+	for _, entry := range entries {
+		node.Content = append(node.Content, entry.key, entry.value)
+	}
+
+	return nil
+}
+
+func encodeScalar(value reflect.Value) (*yaml.Node, error) {
+	var node yaml.Node
+	var data any
+	if value.IsValid() {
+		data = value.Interface()
+	}
+
+	if err := node.Encode(data); err != nil {
+		return nil, err
+	}
+
+	return &node, nil
+}
+
+func hasMarshaler(valueType reflect.Type) bool {
+	return valueType == nodeType ||
+		valueType.Implements(yamlMarshalerType) ||
+		valueType.Implements(textMarshalerType)
+}
@@ -0,0 +1,250 @@
+package confi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultEtcdEndpoint is the default address of etcd v3 HTTP/JSON gateway.
+const DefaultEtcdEndpoint = "http://127.0.0.1:2379"
+
+// EtcdSource reads properties from etcd keys under Prefix via v3 HTTP/JSON gateway.
+// Keys relative to Prefix split by "/" are property paths.
+// Values of keys with extension of a supported format (e.g. "db.yaml") are decoded as documents
+// and mounted at the key path without the extension ("db"). A document stored exactly at Prefix
+// is mounted at the root. Other values are plain strings.
+// Watch tracks changes since the last GetValues call, so the source must be used by pointer.
+type EtcdSource struct {
+	// Endpoint of the gateway (DefaultEtcdEndpoint by default).
+	Endpoint string
+
+	// Prefix of the keys (e.g. "config/app/").
+	Prefix string
+
+	// Header is added to each request (e.g. Authorization with etcd auth token).
+	Header http.Header
+
+	// Client is used for requests (http.DefaultClient by default).
+	Client *http.Client
+
+	mu       sync.Mutex
+	revision int64
+}
+
+type etcdKeyValue struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+type etcdHeader struct {
+	Revision int64 `json:"revision,string"`
+}
+
+func (s *EtcdSource) GetValues(ctx context.Context) (map[string]any, error) {
+	var resp struct {
+		Header etcdHeader     `json:"header"`
+		Kvs    []etcdKeyValue `json:"kvs"`
+	}
+
+	key, rangeEnd := s.keyRange()
+	if err := s.post(ctx, "/v3/kv/range", map[string]any{"key": key, "range_end": rangeEnd}, func(body *json.Decoder) error {
+		return body.Decode(&resp)
+	}); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.revision = resp.Header.Revision
+	s.mu.Unlock()
+
+	var (
+		props     PropertySource
+		documents []map[string]any
+	)
+
+	for _, kv := range resp.Kvs {
+		key := strings.TrimPrefix(string(kv.Key), s.Prefix)
+		if format := strings.TrimPrefix(path.Ext(string(kv.Key)), "."); isSupportedFormat(format) {
+			values, err := InputSource{Input: Reader{R: bytes.NewReader(kv.Value)}, Format: format}.GetValues(ctx)
+			if err != nil {
+				return nil, errors.Wrapf(err, "decode %s", kv.Key)
+			}
+
+			key = strings.Trim(strings.TrimSuffix(key, path.Ext(key)), "/")
+			if key != "" {
+				for _, name := range reverse(strings.Split(key, "/")) {
+					values = map[string]any{name: values}
+				}
+			}
+
+			documents = append(documents, values)
+			continue
+		}
+
+		if key = strings.Trim(key, "/"); key == "" {
+			continue
+		}
+
+		props = append(props, Property{
+			Path:  strings.Split(key, "/"),
+			Value: string(kv.Value),
+		})
+	}
+
+	values, err := props.GetValues(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, document := range documents {
+		values = mergeValues(values, document).(map[string]any)
+	}
+
+	return values, nil
+}
+
+// Watch uses watch API of the gateway and returns when any key under Prefix changes since the last GetValues call.
+// It also returns nil if the revision of the last GetValues call has been compacted, since changes may have been missed.
+// Failed requests are retried with exponential backoff (see DefaultWatchBackoff),
+// so that temporary unavailability does not stop watching.
+// A watch canceled by the server for other reasons results in an error with the cancel reason.
+func (s *EtcdSource) Watch(ctx context.Context) error {
+	s.mu.Lock()
+	revision := s.revision
+	s.mu.Unlock()
+
+	key, rangeEnd := s.keyRange()
+	request := map[string]any{"key": key, "range_end": rangeEnd}
+	if revision > 0 {
+		request["start_revision"] = strconv.FormatInt(revision+1, 10)
+	}
+
+	var (
+		retry    backoff
+		canceled error
+	)
+
+	for {
+		err := s.post(ctx, "/v3/watch", map[string]any{"create_request": request}, func(body *json.Decoder) error {
+			for {
+				var resp struct {
+					Result struct {
+						Events          []json.RawMessage `json:"events"`
+						Canceled        bool              `json:"canceled"`
+						CancelReason    string            `json:"cancel_reason"`
+						CompactRevision int64             `json:"compact_revision,string"`
+					} `json:"result"`
+					Error *struct {
+						Message string `json:"message"`
+					} `json:"error"`
+				}
+
+				if err := body.Decode(&resp); err != nil {
+					return errors.Wrap(err, "decode response")
+				}
+
+				if resp.Error != nil {
+					return errors.New(resp.Error.Message)
+				}
+
+				// the watch has been created, so the connection is healthy
+				retry.reset()
+				if len(resp.Result.Events) > 0 {
+					return nil
+				}
+
+				if resp.Result.Canceled {
+					if resp.Result.CompactRevision > 0 {
+						// the requested revision is not available anymore, so values are reloaded
+						return nil
+					}
+
+					canceled = errors.Errorf("watch canceled: %s", resp.Result.CancelReason)
+					return canceled
+				}
+			}
+		})
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err == nil || canceled != nil {
+			return err
+		}
+
+		if err := retry.wait(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *EtcdSource) String() string {
+	return "etcd " + s.Prefix
+}
+
+// keyRange returns key and range_end for requesting all keys with Prefix.
+func (s *EtcdSource) keyRange() (key, rangeEnd []byte) {
+	key = []byte(s.Prefix)
+	if len(key) == 0 {
+		return []byte{0}, []byte{0}
+	}
+
+	rangeEnd = bytes.Clone(key)
+	for i := len(rangeEnd) - 1; i >= 0; i-- {
+		if rangeEnd[i] < 0xff {
+			rangeEnd[i]++
+			return key, rangeEnd[:i+1]
+		}
+	}
+
+	// all bytes are 0xff, so there is no upper bound
+	return key, []byte{0}
+}
+
+func (s *EtcdSource) post(ctx context.Context, endpoint string, request any, handle func(body *json.Decoder) error) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return errors.Wrap(err, "marshal request")
+	}
+
+	address := s.Endpoint
+	if address == "" {
+		address = DefaultEtcdEndpoint
+	}
+
+	header := s.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	header.Set("Content-Type", "application/json")
+	resp, err := sendRequest(ctx, s.Client, http.MethodPost, strings.TrimSuffix(address, "/")+endpoint, bytes.NewReader(data), header)
+	if err != nil {
+		return err
+	}
+
+	defer CloseQuietly(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return handle(json.NewDecoder(resp.Body))
+}
+
+func reverse(values []string) []string {
+	for i, j := 0, len(values)-1; i < j; i, j = i+1, j-1 {
+		values[i], values[j] = values[j], values[i]
+	}
+
+	return values
+}
@@ -0,0 +1,282 @@
+package confi_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jfk9w-go/confi"
+)
+
+// fakeEtcd implements a subset of etcd v3 HTTP/JSON gateway API.
+type fakeEtcd struct {
+	mu           sync.Mutex
+	kv           map[string]string
+	revision     int64
+	compacted    int64
+	cancelReason string
+	failures     int
+	changed      chan struct{}
+}
+
+func newFakeEtcd(kv map[string]string) *fakeEtcd {
+	return &fakeEtcd{kv: kv, revision: 100, changed: make(chan struct{})}
+}
+
+func (e *fakeEtcd) put(key, value string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.kv[key] = value
+	e.revision++
+	close(e.changed)
+	e.changed = make(chan struct{})
+}
+
+func (e *fakeEtcd) compact() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.compacted = e.revision
+}
+
+func (e *fakeEtcd) fail(requests int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.failures = requests
+}
+
+func (e *fakeEtcd) cancelWatches(reason string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cancelReason = reason
+}
+
+type fakeEtcdRange struct {
+	Key           []byte `json:"key"`
+	RangeEnd      []byte `json:"range_end"`
+	StartRevision int64  `json:"start_revision,string"`
+}
+
+func (r fakeEtcdRange) contains(key string) bool {
+	return bytes.Compare([]byte(key), r.Key) >= 0 &&
+		(bytes.Equal(r.RangeEnd, []byte{0}) || bytes.Compare([]byte(key), r.RangeEnd) < 0)
+}
+
+func (e *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	failed := e.failures > 0
+	if failed {
+		e.failures--
+	}
+
+	e.mu.Unlock()
+	if failed {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/v3/kv/range":
+		var req fakeEtcdRange
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		e.mu.Lock()
+		defer e.mu.Unlock()
+
+		type kv struct {
+			Key   []byte `json:"key"`
+			Value []byte `json:"value"`
+		}
+
+		var kvs []kv
+		for key, value := range e.kv {
+			if req.contains(key) {
+				kvs = append(kvs, kv{Key: []byte(key), Value: []byte(value)})
+			}
+		}
+
+		sort.Slice(kvs, func(i, j int) bool { return string(kvs[i].Key) < string(kvs[j].Key) })
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"header": map[string]any{"revision": strconv.FormatInt(e.revision, 10)},
+			"kvs":    kvs,
+			"count":  strconv.Itoa(len(kvs)),
+		})
+
+	case "/v3/watch":
+		var req struct {
+			CreateRequest fakeEtcdRange `json:"create_request"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		encoder := json.NewEncoder(w)
+		_ = encoder.Encode(map[string]any{"result": map[string]any{"created": true}})
+		w.(http.Flusher).Flush()
+
+		for {
+			e.mu.Lock()
+			revision, compacted, cancelReason, changed := e.revision, e.compacted, e.cancelReason, e.changed
+			e.mu.Unlock()
+
+			if req.CreateRequest.StartRevision > 0 && req.CreateRequest.StartRevision < compacted {
+				_ = encoder.Encode(map[string]any{"result": map[string]any{
+					"canceled":         true,
+					"compact_revision": strconv.FormatInt(compacted, 10),
+					"cancel_reason":    "mvcc: required revision has been compacted",
+				}})
+
+				return
+			}
+
+			if cancelReason != "" {
+				_ = encoder.Encode(map[string]any{"result": map[string]any{
+					"canceled":      true,
+					"cancel_reason": cancelReason,
+				}})
+
+				return
+			}
+
+			if req.CreateRequest.StartRevision > 0 && req.CreateRequest.StartRevision <= revision {
+				_ = encoder.Encode(map[string]any{"result": map[string]any{
+					"events": []map[string]any{{"type": "PUT"}},
+				}})
+
+				return
+			}
+
+			select {
+			case <-changed:
+				if req.CreateRequest.StartRevision == 0 {
+					req.CreateRequest.StartRevision = revision + 1
+				}
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestEtcdSource(t *testing.T) {
+	etcd := newFakeEtcd(map[string]string{
+		"config/app.yaml":         "{log: {level: info}}",
+		"config/app/db/host":      "localhost",
+		"config/app/db/port":      "5432",
+		"config/app/cache.json":   `{"size": 128, "ttl": "1m"}`,
+		"config/app/http/tls.yml": "enabled: true",
+		"config/other/key":        "other",
+	})
+
+	server := httptest.NewServer(etcd)
+	defer server.Close()
+
+	source := &confi.EtcdSource{Endpoint: server.URL, Prefix: "config/app/"}
+	values, err := source.GetValues(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"db":    map[string]any{"host": "localhost", "port": "5432"},
+		"cache": map[string]any{"size": float64(128), "ttl": "1m"},
+		"http":  map[string]any{"tls": map[string]any{"enabled": true}},
+	}, values)
+
+	values, err = (&confi.EtcdSource{Endpoint: server.URL, Prefix: "config/app.yaml"}).GetValues(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"log": map[string]any{"level": "info"}}, values)
+
+	values, err = (&confi.EtcdSource{Endpoint: server.URL, Prefix: "config/missing/"}).GetValues(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, values)
+
+	t.Run("watch", func(t *testing.T) {
+		type Config struct {
+			DB struct {
+				Host string `yaml:"host"`
+			} `yaml:"db"`
+		}
+
+		provider := confi.SourceProviderFunc(func(ctx context.Context) ([]confi.Source, error) {
+			return []confi.Source{source}, nil
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		hosts := make(chan string)
+		done := make(chan error)
+		go func() {
+			done <- confi.Watch[Config](ctx, provider, func(config *Config, _ *confi.Schema, err error) {
+				if assert.NoError(t, err) {
+					hosts <- config.DB.Host
+				}
+			})
+		}()
+
+		assert.Equal(t, "localhost", <-hosts)
+		etcd.put("config/app/db/host", "remote")
+		assert.Equal(t, "remote", <-hosts)
+
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+	})
+
+	t.Run("watch retries", func(t *testing.T) {
+		_, err := source.GetValues(context.Background())
+		require.NoError(t, err)
+
+		etcd.fail(2)
+		etcd.put("config/app/db/host", "retried")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		assert.NoError(t, source.Watch(ctx))
+	})
+
+	t.Run("compacted", func(t *testing.T) {
+		_, err := source.GetValues(context.Background())
+		require.NoError(t, err)
+
+		etcd.put("config/other/key", "changed")
+		etcd.put("config/other/key", "changed again")
+		etcd.compact()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		assert.NoError(t, source.Watch(ctx))
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		_, err := source.GetValues(context.Background())
+		require.NoError(t, err)
+
+		etcd.cancelWatches("permission denied")
+		defer etcd.cancelWatches("")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		err = source.Watch(ctx)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, context.DeadlineExceeded)
+		assert.Contains(t, err.Error(), "watch canceled: permission denied")
+	})
+}
@@ -0,0 +1,166 @@
+package confi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// expand replaces ${...} expressions in text with values returned by lookup and "$$" with "$".
+// Expressions not handled by lookup are left as is.
+func expand(text string, lookup func(expr string) (string, bool, error)) (string, error) {
+	if !strings.Contains(text, "$") {
+		return text, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '$' || i+1 == len(text) {
+			b.WriteByte(text[i])
+			continue
+		}
+
+		switch text[i+1] {
+		case '$':
+			b.WriteByte('$')
+			i++
+
+		case '{':
+			end := closingBrace(text, i+2)
+			if end < 0 {
+				return "", errors.Errorf("unclosed expression at position %d", i)
+			}
+
+			expr := text[i+2 : end]
+			value, ok, err := lookup(expr)
+			if err != nil {
+				return "", errors.Wrapf(err, "${%s}", expr)
+			}
+
+			if ok {
+				b.WriteString(value)
+			} else {
+				b.WriteString(text[i : end+1])
+			}
+
+			i = end
+
+		default:
+			b.WriteByte('$')
+		}
+	}
+
+	return b.String(), nil
+}
+
+func closingBrace(text string, start int) int {
+	depth := 1
+	for i := start; i < len(text); i++ {
+		switch text[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// envValue evaluates shell-like parameter expression:
+// NAME, NAME:-default, NAME-default, NAME:?message and NAME?message.
+// Colon forms treat empty values as unset.
+func envValue(expr string, lookup func(name string) (string, bool)) (string, error) {
+	name, operator, operand := expr, "", ""
+	if i := strings.IndexAny(expr, ":-?"); i >= 0 {
+		name = expr[:i]
+		operator = expr[i : i+1]
+		if operator == ":" && i+1 < len(expr) {
+			operator = expr[i : i+2]
+		}
+
+		operand = expr[i+len(operator):]
+	}
+
+	if name == "" {
+		return "", errors.New("empty variable name")
+	}
+
+	value, ok := lookup(name)
+	if strings.HasPrefix(operator, ":") && value == "" {
+		ok = false
+	}
+
+	if ok {
+		return value, nil
+	}
+
+	switch operator {
+	case ":-", "-":
+		return operand, nil
+	case ":?", "?":
+		if operand == "" {
+			operand = "not set"
+		}
+
+		return "", errors.Errorf("%s: %s", name, operand)
+	case "":
+		return "", nil
+	}
+
+	return "", errors.Errorf("unsupported operator %s", operator)
+}
+
+// transformStrings returns a copy of value with string values replaced by results of fn.
+func transformStrings(value any, path []string, fn func(path []string, text string) (any, error)) (any, error) {
+	switch value := value.(type) {
+	case string:
+		return fn(path, value)
+
+	case map[string]any:
+		target := make(map[string]any, len(value))
+		for key, item := range value {
+			item, err := transformStrings(item, append(path, key), fn)
+			if err != nil {
+				return nil, err
+			}
+
+			target[key] = item
+		}
+
+		return target, nil
+
+	case map[any]any:
+		target := make(map[any]any, len(value))
+		for key, item := range value {
+			item, err := transformStrings(item, append(path, fmt.Sprint(key)), fn)
+			if err != nil {
+				return nil, err
+			}
+
+			target[key] = item
+		}
+
+		return target, nil
+
+	case []any:
+		target := make([]any, len(value))
+		for i, item := range value {
+			item, err := transformStrings(item, append(path, strconv.Itoa(i)), fn)
+			if err != nil {
+				return nil, err
+			}
+
+			target[i] = item
+		}
+
+		return target, nil
+	}
+
+	return value, nil
+}
@@ -0,0 +1,52 @@
+package confi
+
+import (
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// FSSources returns sources for files in fsys matching patterns (see fs.Glob).
+// Files are sorted lexically for each pattern, and format is resolved based on file extension.
+// Each pattern must match at least one file with supported format.
+//
+// It may be used with embed.FS to provide base configuration in DefaultSourceProvider.Base:
+//
+//	//go:embed config/*.yaml
+//	var baseConfig embed.FS
+//
+//	base, err := confi.FSSources(baseConfig, "config/*.yaml")
+func FSSources(fsys fs.FS, patterns ...string) ([]Source, error) {
+	var sources []Source
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "glob %s", pattern)
+		}
+
+		sort.Strings(matches)
+		count := 0
+		for _, match := range matches {
+			format := strings.TrimPrefix(path.Ext(match), ".")
+			if !isSupportedFormat(format) {
+				continue
+			}
+
+			sources = append(sources, InputSource{
+				Input:  FSFile{FS: fsys, Path: match},
+				Format: format,
+			})
+
+			count++
+		}
+
+		if count == 0 {
+			return nil, errors.Errorf("no files match %s", pattern)
+		}
+	}
+
+	return sources, nil
+}
@@ -0,0 +1,68 @@
+package confi_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jfk9w-go/confi"
+)
+
+func TestFSSources(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	fsys := fstest.MapFS{
+		"config/10-base.yaml": {Data: []byte("{host: base, port: 80, user: base}")},
+		"config/20-base.json": {Data: []byte(`{"port": 8080}`)},
+		"config/README.md":    {Data: []byte("readme")},
+		"signed.yaml":         {Data: []byte("user: signed")},
+		"signed.yaml.sig":     {Data: confi.Sign([]byte("user: signed"), private)},
+	}
+
+	sources, err := confi.FSSources(fsys, "config/*")
+	require.NoError(t, err)
+	assert.Equal(t, []confi.Source{
+		confi.InputSource{Input: confi.FSFile{FS: fsys, Path: "config/10-base.yaml"}, Format: "yaml"},
+		confi.InputSource{Input: confi.FSFile{FS: fsys, Path: "config/20-base.json"}, Format: "json"},
+	}, sources)
+
+	_, err = confi.FSSources(fsys, "missing/*.yaml")
+	assert.EqualError(t, err, "no files match missing/*.yaml")
+
+	values, err := confi.InputSource{
+		Input:      confi.FSFile{FS: fsys, Path: "signed.yaml"},
+		Format:     "yaml",
+		PublicKeys: []ed25519.PublicKey{public},
+	}.GetValues(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"user": "signed"}, values)
+
+	t.Run("base", func(t *testing.T) {
+		type Config struct {
+			Host string `yaml:"host"`
+			Port int    `yaml:"port"`
+			User string `yaml:"user"`
+		}
+
+		file := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(file, []byte("host: file"), 0o600))
+
+		config, _, err := confi.FromProvider[Config](context.Background(), &confi.DefaultSourceProvider{
+			EnvPrefix: "app_",
+			Env:       []string{"app_user=env"},
+			Args:      []string{"--config.file=" + file},
+			Base:      sources,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, &Config{Host: "file", Port: 8080, User: "env"}, config)
+	})
+}
@@ -0,0 +1,128 @@
+package confi
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// isGlob reports whether path contains any of glob meta characters.
+func isGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// glob returns regular files matching pattern in lexical order.
+// In addition to filepath.Match syntax, "**" path segment matches any number of directories.
+func glob(pattern string) ([]string, error) {
+	segments := strings.Split(filepath.ToSlash(filepath.Clean(pattern)), "/")
+	rootLen := 0
+	for rootLen < len(segments)-1 && !isGlob(segments[rootLen]) {
+		rootLen++
+	}
+
+	root := filepath.FromSlash(strings.Join(segments[:rootLen], "/"))
+	if root == "" {
+		if filepath.IsAbs(pattern) {
+			root = string(filepath.Separator)
+		} else {
+			root = "."
+		}
+	}
+
+	if !isGlob(pattern) {
+		if info, err := os.Stat(pattern); err != nil || info.IsDir() {
+			return nil, nil
+		}
+
+		return []string{pattern}, nil
+	}
+
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var matches []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if rel != "." && !matchDir(segments[rootLen:], strings.Split(filepath.ToSlash(rel), "/")) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		ok, err := matchSegments(segments[rootLen:], strings.Split(filepath.ToSlash(rel), "/"))
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			matches = append(matches, path)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(matches)
+	return matches, nil
+}
+
+func matchSegments(pattern, path []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(path); i++ {
+				if ok, err := matchSegments(pattern[1:], path[i:]); ok || err != nil {
+					return ok, err
+				}
+			}
+
+			return false, nil
+		}
+
+		if len(path) == 0 {
+			return false, nil
+		}
+
+		if ok, err := filepath.Match(pattern[0], path[0]); !ok || err != nil {
+			return false, err
+		}
+
+		pattern, path = pattern[1:], path[1:]
+	}
+
+	return len(path) == 0, nil
+}
+
+// matchDir reports whether files in directory at path may match pattern.
+func matchDir(pattern, path []string) bool {
+	for i, segment := range path {
+		if i < len(pattern) && pattern[i] == "**" {
+			return true
+		}
+
+		if i >= len(pattern)-1 {
+			return false
+		}
+
+		if ok, _ := filepath.Match(pattern[i], segment); !ok {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,112 @@
+package confi_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jfk9w-go/confi"
+)
+
+func TestDefaultSourceProvider_GetSources_Glob(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"overrides-b.yaml",
+		"overrides-a.yaml",
+		"overrides-c.txt",
+		"other.yaml",
+		"env/prod/overrides-d.yaml",
+		"env/prod/deep/overrides-e.json",
+		"env/test/overrides-f.yaml",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte("{}"), 0o644))
+	}
+
+	file := func(name, format string) confi.Source {
+		return confi.InputSource{Input: confi.File(filepath.Join(dir, filepath.FromSlash(name))), Format: format}
+	}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		expected []confi.Source
+		error    string
+	}{
+		{
+			name:     "single level",
+			patterns: []string{"overrides-*.yaml"},
+			expected: []confi.Source{file("overrides-a.yaml", "yaml"), file("overrides-b.yaml", "yaml")},
+		},
+		{
+			name:     "unsupported skipped",
+			patterns: []string{"overrides-?.*"},
+			expected: []confi.Source{file("overrides-a.yaml", "yaml"), file("overrides-b.yaml", "yaml")},
+		},
+		{
+			name:     "recursive",
+			patterns: []string{"**/overrides-*.[jy]*"},
+			expected: []confi.Source{
+				file("env/prod/deep/overrides-e.json", "json"),
+				file("env/prod/overrides-d.yaml", "yaml"),
+				file("env/test/overrides-f.yaml", "yaml"),
+				file("overrides-a.yaml", "yaml"),
+				file("overrides-b.yaml", "yaml"),
+			},
+		},
+		{
+			name:     "recursive in directory",
+			patterns: []string{"env/prod/**", "other.yaml"},
+			expected: []confi.Source{
+				file("env/prod/deep/overrides-e.json", "json"),
+				file("env/prod/overrides-d.yaml", "yaml"),
+				file("other.yaml", "yaml"),
+			},
+		},
+		{
+			name:     "directory wildcard",
+			patterns: []string{"env/*/overrides-*.yaml"},
+			expected: []confi.Source{file("env/prod/overrides-d.yaml", "yaml"), file("env/test/overrides-f.yaml", "yaml")},
+		},
+		{
+			name:     "optional",
+			patterns: []string{"?missing-*.yaml", "other.yaml"},
+			expected: []confi.Source{file("other.yaml", "yaml")},
+		},
+		{
+			name:     "no matches",
+			patterns: []string{"missing-*.yaml"},
+			error:    "no files match",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &confi.DefaultSourceProvider{}
+			for _, pattern := range tt.patterns {
+				optional := strings.HasPrefix(pattern, "?")
+				pattern = filepath.Join(dir, strings.TrimPrefix(pattern, "?"))
+				if optional {
+					pattern = "?" + pattern
+				}
+
+				provider.Args = append(provider.Args, "--config.file="+pattern)
+			}
+
+			sources, err := provider.GetSources(context.Background())
+			if tt.error != "" {
+				assert.ErrorContains(t, err, tt.error)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, sources)
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package confi
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultHTTPAttempts is the default number of attempts made by HTTPSource for a single request.
+	DefaultHTTPAttempts = 3
+	// DefaultHTTPBackoff is the default delay before the first retry of HTTPSource request.
+	// It is doubled for each retry up to MaxWatchBackoff.
+	DefaultHTTPBackoff = DefaultWatchBackoff
+)
+
+// ContentTypes maps media types to formats used by HTTPSource.
+var ContentTypes = map[string]string{
+	"application/json":       "json",
+	"application/yaml":       "yaml",
+	"application/x-yaml":     "yaml",
+	"text/yaml":              "yaml",
+	"text/x-yaml":            "yaml",
+	"application/x-gob":      "gob",
+	"text/x-java-properties": "properties",
+}
+
+// HTTPSource fetches configuration from URL.
+// Responses are cached according to ETag and Cache-Control headers, and failed requests
+// (network errors, 429 and 5xx responses) are retried with exponential backoff.
+// HTTPSource must be used by pointer, since it keeps the cache between calls.
+type HTTPSource struct {
+	URL string
+
+	// Client is used for requests (http.DefaultClient by default).
+	Client *http.Client
+
+	// Header is added to each request (e.g. for authorization).
+	Header http.Header
+
+	// Format of the response. If it is empty, the format is resolved based on Content-Type header
+	// (see ContentTypes) or URL path extension.
+	Format string
+
+	// Attempts is the maximum number of attempts for a single request (DefaultHTTPAttempts by default).
+	Attempts int
+
+	// Backoff is the delay before the first retry (DefaultHTTPBackoff by default).
+	Backoff time.Duration
+
+	// PollInterval is the interval of checking URL for changes in Watch (DefaultPollInterval by default).
+	PollInterval time.Duration
+
+	mu      sync.Mutex
+	etag    string
+	body    []byte
+	format  string
+	expires time.Time
+}
+
+func (s *HTTPSource) GetValues(ctx context.Context) (map[string]any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.body == nil || !time.Now().Before(s.expires) {
+		if _, err := s.fetch(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return InputSource{
+		Input:  Reader{R: bytes.NewReader(s.body)},
+		Format: s.format,
+	}.GetValues(ctx)
+}
+
+// Watch polls URL until the response changes.
+// Failed requests are ignored, so that temporary unavailability does not stop watching.
+func (s *HTTPSource) Watch(ctx context.Context) error {
+	interval := s.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			s.mu.Lock()
+			changed, err := s.fetch(ctx)
+			s.mu.Unlock()
+			if err == nil && changed {
+				return nil
+			}
+		}
+	}
+}
+
+func (s *HTTPSource) String() string {
+	if u, err := url.Parse(s.URL); err == nil {
+		return u.Redacted()
+	}
+
+	return "http source"
+}
+
+// fetch performs a conditional request with retries and updates the cache.
+// It reports whether the response body has changed.
+func (s *HTTPSource) fetch(ctx context.Context) (bool, error) {
+	attempts := s.Attempts
+	if attempts <= 0 {
+		attempts = DefaultHTTPAttempts
+	}
+
+	var (
+		retry = backoff{initial: s.Backoff}
+		err   error
+	)
+
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if err := retry.wait(ctx); err != nil {
+				return false, err
+			}
+		}
+
+		var (
+			changed   bool
+			retryable bool
+		)
+
+		changed, retryable, err = s.request(ctx)
+		if err == nil || !retryable {
+			return changed, err
+		}
+	}
+
+	return false, err
+}
+
+// request performs a single conditional request.
+// It reports whether the response body has changed and whether the request may be retried on error.
+func (s *HTTPSource) request(ctx context.Context) (bool, bool, error) {
+	header := s.Header.Clone()
+	if s.etag != "" && s.body != nil {
+		if header == nil {
+			header = make(http.Header)
+		}
+
+		header.Set("If-None-Match", s.etag)
+	}
+
+	resp, err := sendRequest(ctx, s.Client, http.MethodGet, s.URL, nil, header)
+	if err != nil {
+		return false, ctx.Err() == nil, err
+	}
+
+	defer CloseQuietly(resp.Body)
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && s.body != nil:
+		s.updateExpires(resp.Header)
+		return false, false, nil
+
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return false, true, errors.Errorf("unexpected status %s", resp.Status)
+
+	case resp.StatusCode != http.StatusOK:
+		return false, false, errors.Errorf("unexpected status %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, ctx.Err() == nil, errors.Wrap(err, "read response")
+	}
+
+	format, err := s.resolveFormat(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return false, false, err
+	}
+
+	changed := s.body == nil || !bytes.Equal(s.body, body) || s.format != format
+	s.body, s.format, s.etag = body, format, resp.Header.Get("ETag")
+	s.updateExpires(resp.Header)
+	return changed, false, nil
+}
+
+func (s *HTTPSource) resolveFormat(contentType string) (string, error) {
+	if s.Format != "" {
+		return s.Format, nil
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if format := ContentTypes[mediaType]; format != "" {
+			return format, nil
+		}
+	}
+
+	if u, err := url.Parse(s.URL); err == nil {
+		if format := strings.TrimPrefix(path.Ext(u.Path), "."); isSupportedFormat(format) {
+			return format, nil
+		}
+	}
+
+	return "", errors.Errorf("unable to resolve format for content type %s", contentType)
+}
+
+// updateExpires sets cache expiration time based on Cache-Control header.
+func (s *HTTPSource) updateExpires(header http.Header) {
+	s.expires = time.Time{}
+	maxAge := 0
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store":
+			s.etag = ""
+			return
+		case "no-cache":
+			return
+		case "max-age":
+			maxAge, _ = strconv.Atoi(strings.Trim(value, `"`))
+		}
+	}
+
+	if maxAge > 0 {
+		s.expires = time.Now().Add(time.Duration(maxAge) * time.Second)
+	}
+}
@@ -0,0 +1,209 @@
+package confi_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jfk9w-go/confi"
+)
+
+type httpConfigServer struct {
+	mu           sync.Mutex
+	body         string
+	contentType  string
+	cacheControl string
+	failures     int
+	requests     int
+	notModified  int
+}
+
+func (s *httpConfigServer) set(body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.body = body
+}
+
+func (s *httpConfigServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	if s.failures > 0 {
+		s.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	etag := `"` + s.body + `"`
+	if s.cacheControl != "" {
+		w.Header().Set("Cache-Control", s.cacheControl)
+	}
+
+	if r.Header.Get("If-None-Match") == etag {
+		s.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Content-Type", s.contentType)
+	_, _ = w.Write([]byte(s.body))
+}
+
+func TestHTTPSource(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("etag", func(t *testing.T) {
+		handler := &httpConfigServer{body: `{"key": "value"}`, contentType: "application/json; charset=utf-8"}
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		source := &confi.HTTPSource{URL: server.URL}
+		for i := 0; i < 2; i++ {
+			values, err := source.GetValues(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, map[string]any{"key": "value"}, values)
+		}
+
+		assert.Equal(t, 2, handler.requests)
+		assert.Equal(t, 1, handler.notModified)
+
+		handler.set(`{"key": "changed"}`)
+		values, err := source.GetValues(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"key": "changed"}, values)
+	})
+
+	t.Run("cache control", func(t *testing.T) {
+		handler := &httpConfigServer{body: "key: value", cacheControl: "public, max-age=60"}
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		source := &confi.HTTPSource{URL: server.URL + "/config.yaml"}
+		for i := 0; i < 3; i++ {
+			values, err := source.GetValues(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, map[string]any{"key": "value"}, values)
+		}
+
+		assert.Equal(t, 1, handler.requests)
+	})
+
+	t.Run("no store", func(t *testing.T) {
+		handler := &httpConfigServer{body: "key: value", contentType: "application/yaml", cacheControl: "no-store"}
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		source := &confi.HTTPSource{URL: server.URL}
+		for i := 0; i < 2; i++ {
+			_, err := source.GetValues(ctx)
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, 2, handler.requests)
+		assert.Equal(t, 0, handler.notModified)
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		handler := &httpConfigServer{body: "key=value", contentType: "text/x-java-properties", failures: 2}
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		source := &confi.HTTPSource{URL: server.URL, Backoff: time.Millisecond}
+		values, err := source.GetValues(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"key": "value"}, values)
+		assert.Equal(t, 3, handler.requests)
+
+		handler.failures = 3
+		_, err = (&confi.HTTPSource{URL: server.URL, Backoff: time.Millisecond}).GetValues(ctx)
+		assert.ErrorContains(t, err, "unexpected status 503 Service Unavailable")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			http.NotFound(w, r)
+		}))
+
+		defer server.Close()
+
+		_, err := (&confi.HTTPSource{URL: server.URL, Backoff: time.Millisecond}).GetValues(ctx)
+		assert.ErrorContains(t, err, "unexpected status 404 Not Found")
+		assert.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		server := httptest.NewServer(&httpConfigServer{body: "key: value", contentType: "text/plain"})
+		defer server.Close()
+
+		_, err := (&confi.HTTPSource{URL: server.URL + "/config"}).GetValues(ctx)
+		assert.EqualError(t, err, "unable to resolve format for content type text/plain")
+
+		values, err := (&confi.HTTPSource{URL: server.URL + "/config", Format: "yaml"}).GetValues(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"key": "value"}, values)
+	})
+
+	t.Run("context", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+
+		defer server.Close()
+		defer close(release)
+
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := (&confi.HTTPSource{URL: server.URL}).GetValues(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("watch", func(t *testing.T) {
+		type Config struct {
+			Key string `yaml:"key"`
+		}
+
+		handler := &httpConfigServer{body: `{"key": "first"}`, contentType: "application/json"}
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		source := &confi.HTTPSource{URL: server.URL, PollInterval: 10 * time.Millisecond}
+		provider := confi.SourceProviderFunc(func(ctx context.Context) ([]confi.Source, error) {
+			return []confi.Source{source}, nil
+		})
+
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		keys := make(chan string)
+		done := make(chan error)
+		go func() {
+			done <- confi.Watch[Config](ctx, provider, func(config *Config, _ *confi.Schema, err error) {
+				if assert.NoError(t, err) {
+					keys <- config.Key
+				}
+			})
+		}()
+
+		assert.Equal(t, "first", <-keys)
+		handler.set(`{"key": "second"}`)
+		assert.Equal(t, "second", <-keys)
+
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+	})
+}
@@ -2,6 +2,7 @@ package confi
 
 import (
 	"io"
+	"io/fs"
 	"os"
 )
 
@@ -14,6 +15,14 @@ type File string
 func (f File) Path() string               { return string(f) }
 func (f File) Reader() (io.Reader, error) { return os.Open(f.Path()) }
 
+// FSFile is a file in fs.FS (such as embed.FS).
+type FSFile struct {
+	FS   fs.FS
+	Path string
+}
+
+func (f FSFile) Reader() (io.Reader, error) { return f.FS.Open(f.Path) }
+
 type Reader struct {
 	R io.Reader
 }
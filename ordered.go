@@ -0,0 +1,206 @@
+package confi
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Entry is a key-value pair of OrderedMap.
+type Entry[V any] struct {
+	Key   string
+	Value V
+}
+
+// OrderedMap is a string-keyed map which preserves insertion order.
+// It is encoded to YAML and JSON as a mapping with keys in the same order.
+// The zero value is an empty map ready to use.
+type OrderedMap[V any] struct {
+	entries []Entry[V]
+	index   map[string]int
+}
+
+// NewOrderedMap creates an OrderedMap from entries. Later entries replace earlier ones with the same key.
+func NewOrderedMap[V any](entries ...Entry[V]) *OrderedMap[V] {
+	m := &OrderedMap[V]{}
+	for _, entry := range entries {
+		m.Set(entry.Key, entry.Value)
+	}
+
+	return m
+}
+
+// Get returns the value stored for key.
+func (m *OrderedMap[V]) Get(key string) (V, bool) {
+	if i, ok := m.find(key); ok {
+		return m.entries[i].Value, true
+	}
+
+	var zero V
+	return zero, false
+}
+
+// Set replaces the value stored for key in place or appends a new entry if key is missing.
+func (m *OrderedMap[V]) Set(key string, value V) {
+	if i, ok := m.find(key); ok {
+		m.entries[i].Value = value
+		return
+	}
+
+	if m.index == nil {
+		m.index = make(map[string]int)
+	}
+
+	m.index[key] = len(m.entries)
+	m.entries = append(m.entries, Entry[V]{Key: key, Value: value})
+}
+
+// Len returns the number of entries.
+func (m *OrderedMap[V]) Len() int {
+	if m == nil {
+		return 0
+	}
+
+	return len(m.entries)
+}
+
+// Entries returns entries in order.
+func (m *OrderedMap[V]) Entries() []Entry[V] {
+	if m == nil {
+		return nil
+	}
+
+	return m.entries
+}
+
+// Keys returns keys in order.
+func (m *OrderedMap[V]) Keys() []string {
+	keys := make([]string, m.Len())
+	for i, entry := range m.Entries() {
+		keys[i] = entry.Key
+	}
+
+	return keys
+}
+
+func (m *OrderedMap[V]) find(key string) (int, bool) {
+	if m == nil {
+		return 0, false
+	}
+
+	// index may be shared with a copy of the map, so it is checked against entries
+	i, ok := m.index[key]
+	return i, ok && i < len(m.entries) && m.entries[i].Key == key
+}
+
+func (m OrderedMap[V]) MarshalYAML() (any, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, entry := range m.entries {
+		var value yaml.Node
+		if err := value.Encode(entry.Value); err != nil {
+			return nil, errors.Wrapf(err, "on key %s", entry.Key)
+		}
+
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: entry.Key}, &value)
+	}
+
+	return node, nil
+}
+
+func (m *OrderedMap[V]) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return errors.Errorf("expected mapping, got %s", node.ShortTag())
+	}
+
+	*m = OrderedMap[V]{}
+	for i := 0; i < len(node.Content); i += 2 {
+		var value V
+		if err := node.Content[i+1].Decode(&value); err != nil {
+			return errors.Wrapf(err, "on key %s", node.Content[i].Value)
+		}
+
+		m.Set(node.Content[i].Value, value)
+	}
+
+	return nil
+}
+
+func (m OrderedMap[V]) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, entry := range m.entries {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		key, err := json.Marshal(entry.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := json.Marshal(entry.Value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "on key %s", entry.Key)
+		}
+
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+func (m *OrderedMap[V]) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil {
+		return err
+	} else if token != json.Delim('{') {
+		return errors.Errorf("expected object, got %v", token)
+	}
+
+	*m = OrderedMap[V]{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		key, _ := token.(string)
+		var value V
+		if err := decoder.Decode(&value); err != nil {
+			return errors.Wrapf(err, "on key %s", key)
+		}
+
+		m.Set(key, value)
+	}
+
+	_, err := decoder.Token()
+	return err
+}
+
+// plainValue replaces ordered maps in value with plain maps recursively.
+func plainValue(value any) any {
+	switch value := value.(type) {
+	case OrderedMap[any]:
+		target := make(map[string]any, len(value.entries))
+		for _, entry := range value.entries {
+			target[entry.Key] = plainValue(entry.Value)
+		}
+
+		return target
+
+	case []any:
+		target := make([]any, len(value))
+		for i, item := range value {
+			target[i] = plainValue(item)
+		}
+
+		return target
+	}
+
+	return value
+}
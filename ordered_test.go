@@ -0,0 +1,97 @@
+package confi_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/jfk9w-go/confi"
+)
+
+func TestOrderedMap(t *testing.T) {
+	var m confi.OrderedMap[int]
+	m.Set("c", 1)
+	m.Set("a", 2)
+	m.Set("b", 3)
+	m.Set("a", 4)
+
+	assert.Equal(t, []string{"c", "a", "b"}, m.Keys())
+	value, ok := m.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 4, value)
+
+	t.Run("json", func(t *testing.T) {
+		data, err := json.Marshal(m)
+		require.NoError(t, err)
+		assert.Equal(t, `{"c":1,"a":4,"b":3}`, string(data))
+
+		var actual confi.OrderedMap[int]
+		require.NoError(t, json.Unmarshal(data, &actual))
+		assert.Equal(t, m, actual)
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		data, err := yaml.Marshal(m)
+		require.NoError(t, err)
+		assert.Equal(t, "c: 1\na: 4\nb: 3\n", string(data))
+
+		var actual confi.OrderedMap[int]
+		require.NoError(t, yaml.Unmarshal(data, &actual))
+		assert.Equal(t, m, actual)
+	})
+}
+
+func TestSchema_PropertyOrder(t *testing.T) {
+	type Embedded struct {
+		B string `yaml:"b"`
+		C string `yaml:"c"`
+	}
+
+	type Value struct {
+		Z        string `yaml:"z"`
+		Embedded `yaml:",inline"`
+		A        string `yaml:"a"`
+	}
+
+	schema, err := confi.GenerateSchema(Value{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"z", "b", "c", "a"}, schema.Properties.Keys())
+
+	for name, codec := range map[string]confi.Codec{"json": confi.JSON, "yaml": confi.YAML} {
+		t.Run(name, func(t *testing.T) {
+			var b bytes.Buffer
+			require.NoError(t, codec.Marshal(schema, &b))
+
+			var actual struct {
+				Properties confi.OrderedMap[any] `yaml:"properties"`
+			}
+
+			require.NoError(t, yaml.Unmarshal(b.Bytes(), &actual))
+			assert.Equal(t, []string{"z", "b", "c", "a"}, actual.Properties.Keys())
+		})
+	}
+}
+
+func TestCodec_Ordered(t *testing.T) {
+	value := map[string]any{"b": map[string]any{"c": 1}, "a": []any{map[string]any{"d": 2}}}
+
+	var received any
+	codec := confi.Codec{MarshalFn: func(value any, _ io.Writer) error {
+		received = value
+		return nil
+	}}
+
+	require.NoError(t, codec.Marshal(value, io.Discard))
+	assert.Equal(t, value, received)
+
+	codec.Ordered = true
+	require.NoError(t, codec.Marshal(value, io.Discard))
+	ordered, ok := received.(confi.OrderedMap[any])
+	require.True(t, ok)
+	assert.Equal(t, []string{"a", "b"}, ordered.Keys())
+}
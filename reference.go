@@ -0,0 +1,262 @@
+package confi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// resolver expands ${...} expressions in values with environment variables and references to other values.
+// It is used both for values from sources and for templates in default tags.
+type resolver struct {
+	// find returns the value stored at path. Values which are not found are resolved from schema defaults.
+	find   func(path []string) (any, bool)
+	schema *Schema
+
+	// lookupEnv resolves ${env:NAME} expressions and also ${NAME} expressions if bareEnv is set.
+	lookupEnv func(name string) (string, bool)
+	bareEnv   bool
+
+	// refs enables ${ref:path} expressions.
+	refs bool
+
+	// final means that values returned by find are already resolved, so they are used as is.
+	final bool
+
+	// resolveSecret resolves secret URIs in referenced values before they are copied, if set.
+	resolveSecret func(path []string, text string) (string, error)
+
+	chain []string
+}
+
+func newResolver(values []map[string]any, schema *Schema, options options) *resolver {
+	var merged any
+	for _, values := range values {
+		merged = mergeValues(merged, values)
+	}
+
+	return &resolver{
+		find:      func(path []string) (any, bool) { return findValue(merged, path) },
+		schema:    schema,
+		lookupEnv: options.lookupEnv,
+		bareEnv:   true,
+		refs:      options.references,
+	}
+}
+
+func (r *resolver) resolve(values map[string]any) (map[string]any, error) {
+	result, err := transformStrings(values, nil, r.resolveString)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(map[string]any), nil
+}
+
+func (r *resolver) resolveString(path []string, text string) (any, error) {
+	key := strings.Join(path, ".")
+	r.chain = append(r.chain, key)
+	defer func() { r.chain = r.chain[:len(r.chain)-1] }()
+
+	value, err := r.resolveTemplate(text)
+	if err != nil {
+		return nil, errors.Wrapf(err, "on %s", key)
+	}
+
+	return value, nil
+}
+
+// resolveTemplate expands text. A text consisting of a single reference is resolved to the referenced value as is.
+func (r *resolver) resolveTemplate(text string) (any, error) {
+	if ref, ok := singleReference(text); ok && r.refs {
+		return r.reference(ref)
+	}
+
+	return expand(text, r.lookup)
+}
+
+func (r *resolver) lookup(expr string) (string, bool, error) {
+	if ref, ok := strings.CutPrefix(expr, "ref:"); ok {
+		if !r.refs {
+			return "", false, nil
+		}
+
+		if schema := r.schema.Lookup(strings.Split(ref, ".")...); schema != nil {
+			if schema.Type == "object" || schema.Type == "array" {
+				return "", false, errors.Errorf("unable to interpolate %s of type %s into string", ref, schema.Type)
+			}
+		}
+
+		value, err := r.reference(ref)
+		if err != nil {
+			return "", false, err
+		}
+
+		text, err := formatScalar(value)
+		if err != nil {
+			return "", false, errors.Wrapf(err, "unable to interpolate %s into string", ref)
+		}
+
+		return text, true, nil
+	}
+
+	name, ok := strings.CutPrefix(expr, "env:")
+	if r.lookupEnv == nil || !ok && !r.bareEnv {
+		return "", false, nil
+	}
+
+	value, err := envValue(name, r.lookupEnv)
+	return value, true, err
+}
+
+func (r *resolver) reference(ref string) (any, error) {
+	for i, key := range r.chain {
+		if key == ref || strings.HasPrefix(key, ref+".") {
+			return nil, errors.Errorf("reference cycle: %s -> %s", strings.Join(r.chain[i:], " -> "), ref)
+		}
+	}
+
+	path := strings.Split(ref, ".")
+	value, ok := r.find(path)
+	if !ok {
+		if schema := r.schema.Lookup(path...); schema != nil {
+			switch {
+			case schema.Default != nil:
+				return schema.Default, nil
+			case schema.defaultTemplate != "":
+				return r.resolveString(path, schema.defaultTemplate)
+			}
+		}
+
+		return nil, errors.Errorf("reference %s not found", ref)
+	}
+
+	if r.final {
+		return value, nil
+	}
+
+	value, err := transformStrings(value, path, r.resolveString)
+	if err != nil || r.resolveSecret == nil {
+		return value, err
+	}
+
+	return transformStrings(value, path, func(path []string, text string) (any, error) {
+		return r.resolveSecret(path, text)
+	})
+}
+
+// formatScalar formats value for interpolation into a string. Secret values are revealed.
+func formatScalar(value any) (string, error) {
+	if secret, ok := value.(secret); ok {
+		value = secret.secretValue()
+	}
+
+	switch value := value.(type) {
+	case string:
+		return value, nil
+	case nil:
+		return "", nil
+	}
+
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return "", err
+	}
+
+	if node.Kind != yaml.ScalarNode {
+		return "", errors.New("not a scalar value")
+	}
+
+	return node.Value, nil
+}
+
+// singleReference checks if text consists of a single ${ref:...} expression.
+func singleReference(text string) (string, bool) {
+	if !strings.HasPrefix(text, "${ref:") || closingBrace(text, 2) != len(text)-1 {
+		return "", false
+	}
+
+	return text[6 : len(text)-1], true
+}
+
+// mergeValues merges source mappings into target mappings recursively.
+// Other values from source replace target values.
+func mergeValues(target, source any) any {
+	sourceMap, ok := stringMap(source)
+	if !ok {
+		return source
+	}
+
+	targetMap, ok := stringMap(target)
+	if !ok {
+		targetMap = make(map[string]any, len(sourceMap))
+	}
+
+	merged := make(map[string]any, len(targetMap)+len(sourceMap))
+	for key, value := range targetMap {
+		merged[key] = value
+	}
+
+	for key, value := range sourceMap {
+		merged[key] = mergeValues(merged[key], value)
+	}
+
+	return merged
+}
+
+func stringMap(value any) (map[string]any, bool) {
+	switch value := value.(type) {
+	case map[string]any:
+		return value, true
+	case map[any]any:
+		target := make(map[string]any, len(value))
+		for key, item := range value {
+			target[fmt.Sprint(key)] = item
+		}
+
+		return target, true
+	}
+
+	return nil, false
+}
+
+// findValue returns value stored at path. Keys are matched case-insensitively if there is no exact match.
+func findValue(value any, path []string) (any, bool) {
+	for _, name := range path {
+		if values, ok := stringMap(value); ok {
+			item, ok := values[name]
+			if !ok {
+				for key, candidate := range values {
+					if strings.EqualFold(key, name) {
+						item, ok = candidate, true
+						break
+					}
+				}
+			}
+
+			if !ok {
+				return nil, false
+			}
+
+			value = item
+			continue
+		}
+
+		if values, ok := value.([]any); ok {
+			index, err := strconv.Atoi(name)
+			if err != nil || index < 0 || index >= len(values) {
+				return nil, false
+			}
+
+			value = values[index]
+			continue
+		}
+
+		return nil, false
+	}
+
+	return value, true
+}
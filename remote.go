@@ -0,0 +1,64 @@
+package confi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// sendRequest sends a request to a remote source with header added to it.
+// http.DefaultClient is used if client is nil.
+func sendRequest(ctx context.Context, client *http.Client, method, endpoint string, body io.Reader, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, errors.Wrap(err, "create request")
+	}
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "send request")
+	}
+
+	return resp, nil
+}
+
+// backoff is the retry policy of remote sources and Watch: the delay starts at initial
+// (DefaultWatchBackoff by default) and is doubled for each retry up to MaxWatchBackoff.
+type backoff struct {
+	initial time.Duration
+	delay   time.Duration
+}
+
+// wait blocks for the current delay or until ctx is done.
+func (b *backoff) wait(ctx context.Context) error {
+	if b.delay <= 0 {
+		b.reset()
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(b.delay):
+		b.delay = min(2*b.delay, MaxWatchBackoff)
+		return nil
+	}
+}
+
+// reset restores the initial delay after a successful attempt.
+func (b *backoff) reset() {
+	b.delay = b.initial
+	if b.delay <= 0 {
+		b.delay = DefaultWatchBackoff
+	}
+}
@@ -1,7 +1,9 @@
 package confi
 
 import (
+	"encoding/json"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,75 +24,168 @@ type enum interface {
 	SchemaEnum() any
 }
 
+// Draft202012 is the dialect URI of JSON Schema draft 2020-12.
+const Draft202012 = "https://json-schema.org/draft/2020-12/schema"
+
 type Schema struct {
-	Type                 string            `yaml:"type"`
-	Items                *Schema           `yaml:"items,omitempty"`
-	Properties           map[string]Schema `yaml:"properties,omitempty"`
-	AdditionalProperties any               `yaml:"additionalProperties,omitempty"`
-	Required             []string          `yaml:"required,omitempty"`
+	Dialect              string              `yaml:"$schema,omitempty" json:"$schema,omitempty"`
+	ID                   string              `yaml:"$id,omitempty" json:"$id,omitempty"`
+	Type                 string              `yaml:"type,omitempty" json:"type,omitempty"`
+	Items                *Schema             `yaml:"items,omitempty" json:"items,omitempty"`
+	Properties           *OrderedMap[Schema] `yaml:"properties,omitempty" json:"properties,omitempty"`
+	AdditionalProperties any                 `yaml:"additionalProperties,omitempty" json:"additionalProperties,omitempty"`
+	Required             []string            `yaml:"required,omitempty" json:"required,omitempty"`
 
 	// properties below are applied to primitive or inner types
-	Enum             any     `yaml:"enum,omitempty" prop:"inner,array"`
-	Examples         any     `yaml:"examples,omitempty" prop:"inner,array"`
-	Pattern          string  `yaml:"pattern,omitempty" prop:"inner"`
-	Format           string  `yaml:"format,omitempty" prop:"inner" alias:"fmt"`
-	Minimum          any     `yaml:"minimum,omitempty" prop:"inner" alias:"min"`
-	ExclusiveMinimum any     `yaml:"exclusiveMinimum,omitempty" prop:"inner" alias:"xmin"`
-	Maximum          any     `yaml:"maximum,omitempty" prop:"inner" alias:"max"`
-	ExclusiveMaximum any     `yaml:"exclusiveMaximum,omitempty" prop:"inner" alias:"xmax"`
-	MultipleOf       any     `yaml:"multipleOf,omitempty" prop:"inner" alias:"mul"`
-	MinLength        uint64  `yaml:"minLength,omitempty" prop:"inner" alias:"minlen"`
-	MaxLength        *uint64 `yaml:"maxLength,omitempty" prop:"inner" alias:"maxlen"`
+	Const            any     `yaml:"const,omitempty" json:"const,omitempty" prop:"inner"`
+	Enum             any     `yaml:"enum,omitempty" json:"enum,omitempty" prop:"inner,array"`
+	Examples         any     `yaml:"examples,omitempty" json:"examples,omitempty" prop:"inner,array"`
+	Pattern          string  `yaml:"pattern,omitempty" json:"pattern,omitempty" prop:"inner"`
+	Format           string  `yaml:"format,omitempty" json:"format,omitempty" prop:"inner" alias:"fmt"`
+	Minimum          any     `yaml:"minimum,omitempty" json:"minimum,omitempty" prop:"inner" alias:"min"`
+	ExclusiveMinimum any     `yaml:"exclusiveMinimum,omitempty" json:"exclusiveMinimum,omitempty" prop:"inner" alias:"xmin"`
+	Maximum          any     `yaml:"maximum,omitempty" json:"maximum,omitempty" prop:"inner" alias:"max"`
+	ExclusiveMaximum any     `yaml:"exclusiveMaximum,omitempty" json:"exclusiveMaximum,omitempty" prop:"inner" alias:"xmax"`
+	MultipleOf       any     `yaml:"multipleOf,omitempty" json:"multipleOf,omitempty" prop:"inner" alias:"mul"`
+	MinLength        uint64  `yaml:"minLength,omitempty" json:"minLength,omitempty" prop:"inner" alias:"minlen"`
+	MaxLength        *uint64 `yaml:"maxLength,omitempty" json:"maxLength,omitempty" prop:"inner" alias:"maxlen"`
 
 	// properties below are applied to primitive or outer types
-	Description   string  `yaml:"description,omitempty" prop:"outer" alias:"desc,doc"`
-	Default       any     `yaml:"default,omitempty" prop:"outer" alias:"def"`
-	MinItems      uint64  `yaml:"minItems,omitempty" prop:"outer" alias:"minsize"`
-	MaxItems      *uint64 `yaml:"maxItems,omitempty" prop:"outer" alias:"maxsize"`
-	UniqueItems   bool    `yaml:"uniqueItems,omitempty" prop:"outer" alias:"unique"`
-	MinProperties uint64  `yaml:"minProperties,omitempty" prop:"outer" alias:"minprops"`
-	MaxProperties *uint64 `yaml:"maxProperties,omitempty" prop:"outer" alias:"maxprops"`
+	Title         string  `yaml:"title,omitempty" json:"title,omitempty" prop:"outer"`
+	Description   string  `yaml:"description,omitempty" json:"description,omitempty" prop:"outer" alias:"desc,doc"`
+	Comment       string  `yaml:"$comment,omitempty" json:"$comment,omitempty" prop:"outer" alias:"comment"`
+	Default       any     `yaml:"default,omitempty" json:"default,omitempty" prop:"outer" alias:"def"`
+	Deprecated    bool    `yaml:"deprecated,omitempty" json:"deprecated,omitempty" prop:"outer"`
+	ReadOnly      bool    `yaml:"readOnly,omitempty" json:"readOnly,omitempty" prop:"outer" alias:"readonly"`
+	WriteOnly     bool    `yaml:"writeOnly,omitempty" json:"writeOnly,omitempty" prop:"outer" alias:"writeonly"`
+	MinItems      uint64  `yaml:"minItems,omitempty" json:"minItems,omitempty" prop:"outer" alias:"minsize"`
+	MaxItems      *uint64 `yaml:"maxItems,omitempty" json:"maxItems,omitempty" prop:"outer" alias:"maxsize"`
+	UniqueItems   bool    `yaml:"uniqueItems,omitempty" json:"uniqueItems,omitempty" prop:"outer" alias:"unique"`
+	MinProperties uint64  `yaml:"minProperties,omitempty" json:"minProperties,omitempty" prop:"outer" alias:"minprops"`
+	MaxProperties *uint64 `yaml:"maxProperties,omitempty" json:"maxProperties,omitempty" prop:"outer" alias:"maxprops"`
+
+	// AllowFile allows the value to be read from a file referenced by <key>_FILE environment variable
+	// or --<key>.file command-line option.
+	AllowFile bool `yaml:"x-file,omitempty" json:"x-file,omitempty" prop:"outer" alias:"file"`
+
+	// defaultTemplate is a default value containing ${ref:...} or ${env:...} expressions which are evaluated in ApplyDefaults.
+	defaultTemplate string
+
+	// tags are struct tags the schema was generated with. They are used to apply defaults and encode values.
+	tags tagNames
 }
 
-func (s *Schema) ApplyDefaults(source any) error {
-	return s.applyDefaults(reflect.ValueOf(source))
+// MarshalYAML encodes the schema with values of const, default, enum and examples
+// encoded using the struct tags the schema was generated with.
+func (s Schema) MarshalYAML() (any, error) {
+	type plain Schema
+	result := plain(s)
+	for _, value := range []*any{&result.Const, &result.Default, &result.Enum, &result.Examples} {
+		if *value == nil {
+			continue
+		}
+
+		node, err := s.tags.encodeNode(reflect.ValueOf(*value))
+		if err != nil {
+			return nil, err
+		}
+
+		*value = node
+	}
+
+	return result, nil
 }
 
-var errUnaddressable = errors.New(
-	`unable to set default value for unaddressable value (use pointer values if this is in a map or remove "default" tag)`)
+// MarshalJSON encodes the schema using the same keywords as YAML encoding does.
+// Default values, enums and examples are encoded with their YAML representation.
+func (s Schema) MarshalJSON() ([]byte, error) {
+	var node yaml.Node
+	if err := node.Encode(s); err != nil {
+		return nil, errors.Wrap(err, "encode schema")
+	}
 
-func (s *Schema) applyDefaults(value reflect.Value) error {
-	if s.Default != nil && value.IsZero() {
-		if !value.CanAddr() {
-			return errUnaddressable
-		}
+	value, err := nodeValue(&node)
+	if err != nil {
+		return nil, err
+	}
 
-		value.Set(reflect.ValueOf(s.Default))
-		return nil
+	return json.Marshal(value)
+}
+
+// Defaulter is implemented by types which compute their own default values.
+// SetDefaults is called before default values from tags are applied,
+// so tag defaults are applied only to fields left zero by SetDefaults.
+type Defaulter interface {
+	SetDefaults()
+}
+
+// ApplyDefaults sets default values to zero fields of source.
+// Default tags containing ${ref:...} or ${env:...} expressions are templates which are evaluated
+// after all static defaults are applied. Templates may refer to other properties by their dot-separated path
+// (${ref:http.host}) and to environment variables (${env:HOSTNAME} or ${env:HOSTNAME:-localhost}).
+// Other ${...} expressions are left as is, and "$$" is replaced with "$".
+func (s *Schema) ApplyDefaults(source any) error {
+	value := reflect.ValueOf(source)
+	pass := newDefaultsPass(value, s)
+	if err := s.applyDefaults(value, pass); err != nil {
+		return err
 	}
 
-	if value.Kind() == reflect.Ptr {
-		if value.IsNil() {
-			if s.Default == nil {
-				return nil
+	pass.templates = true
+	return s.applyDefaults(value, pass)
+}
+
+var errUnaddressable = errors.New(
+	`unable to set default value for unaddressable value (pass a pointer to ApplyDefaults or remove "default" tag)`)
+
+func (s *Schema) applyDefaults(value reflect.Value, pass *defaultsPass) error {
+	if value.IsZero() {
+		switch {
+		case !pass.templates && s.Default != nil:
+			if !value.CanAddr() {
+				return errUnaddressable
 			}
 
+			value.Set(reflect.ValueOf(s.Default))
+			return nil
+
+		case pass.templates && s.defaultTemplate != "":
 			if !value.CanAddr() {
 				return errUnaddressable
 			}
 
-			resolvedType := indirectType(value.Type())
-			value.Set(reflect.New(resolvedType))
+			return pass.setTemplate(value, s.defaultTemplate)
+		}
+	}
+
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
 		}
 
 		value = value.Elem()
 	}
 
+	if _, ok := value.Interface().(secret); ok {
+		return nil
+	}
+
+	if !pass.templates && value.Kind() == reflect.Struct && value.CanAddr() {
+		if defaulter, ok := value.Addr().Interface().(Defaulter); ok {
+			defaulter.SetDefaults()
+		}
+	}
+
 	if schema, ok := s.AdditionalProperties.(*Schema); ok {
 		for _, key := range value.MapKeys() {
-			if err := schema.applyDefaults(value.MapIndex(key)); err != nil {
+			// map values are not addressable, so defaults are applied to a copy which is stored back
+			elem := reflect.New(value.Type().Elem()).Elem()
+			elem.Set(value.MapIndex(key))
+			if err := schema.applyDefaults(elem, pass); err != nil {
 				return errors.Wrapf(err, "on key %v", key.Interface())
 			}
+
+			value.SetMapIndex(key, elem)
 		}
 
 		return nil
@@ -98,7 +193,7 @@ func (s *Schema) applyDefaults(value reflect.Value) error {
 
 	if schema := s.Items; schema != nil {
 		for i := 0; i < value.Len(); i++ {
-			if err := schema.applyDefaults(value.Index(i)); err != nil {
+			if err := schema.applyDefaults(value.Index(i), pass); err != nil {
 				return errors.Wrapf(err, "on index %d", i)
 			}
 		}
@@ -109,17 +204,21 @@ func (s *Schema) applyDefaults(value reflect.Value) error {
 	if properties := s.Properties; properties != nil {
 		for fieldNum := 0; fieldNum < value.NumField(); fieldNum++ {
 			field := value.Type().Field(fieldNum)
-			options := getYAMLOptions(field)
+			options := s.tags.fieldOptions(field)
+			if options.skip {
+				continue
+			}
+
 			if options.inline {
-				if err := s.applyDefaults(value.Field(fieldNum)); err != nil {
+				if err := s.applyDefaults(value.Field(fieldNum), pass); err != nil {
 					return errors.Wrapf(err, "on embedded field %s", field.Name)
 				}
 
 				continue
 			}
 
-			property := properties[options.name]
-			if err := property.applyDefaults(value.Field(fieldNum)); err != nil {
+			property, _ := properties.Get(options.name)
+			if err := property.applyDefaults(value.Field(fieldNum), pass); err != nil {
 				return errors.Wrapf(err, "on field %s", options.name)
 			}
 		}
@@ -128,11 +227,83 @@ func (s *Schema) applyDefaults(value reflect.Value) error {
 	return nil
 }
 
-func GenerateSchema(value any) (*Schema, error) {
-	return makeSchema(reflect.TypeOf(value), "")
+// Lookup returns schema of the property located at path or nil if there is no such property.
+// Property names are matched case-insensitively if there is no exact match.
+func (s *Schema) Lookup(path ...string) *Schema {
+	s, _ = s.lookup(path)
+	return s
+}
+
+// lookup works like Lookup, but also returns path with property names as they are declared in the schema.
+func (s *Schema) lookup(path []string) (*Schema, []string) {
+	resolved := make([]string, 0, len(path))
+	for _, name := range path {
+		if s == nil {
+			return nil, nil
+		}
+
+		switch {
+		case s.Properties != nil:
+			property, ok := s.Properties.Get(name)
+			if !ok {
+				for _, entry := range s.Properties.Entries() {
+					if strings.EqualFold(entry.Key, name) {
+						name, property, ok = entry.Key, entry.Value, true
+						break
+					}
+				}
+			}
+
+			if !ok {
+				return nil, nil
+			}
+
+			s = &property
+
+		case s.Items != nil:
+			if _, err := strconv.Atoi(name); err != nil {
+				return nil, nil
+			}
+
+			s = s.Items
+
+		default:
+			s, _ = s.AdditionalProperties.(*Schema)
+		}
+
+		resolved = append(resolved, name)
+	}
+
+	return s, resolved
+}
+
+// GenerateSchema generates schema for the type of value.
+// Property names are read from struct tags set by WithTagNames (yaml and json by default).
+func GenerateSchema(value any, opts ...Option) (*Schema, error) {
+	return schemaGenerator{tags: newOptions(opts).tagNames}.generate(value)
+}
+
+// isTemplate reports whether default tag value contains explicit reference or environment variable expressions.
+func isTemplate(value string) bool {
+	return strings.Contains(value, "${ref:") || strings.Contains(value, "${env:")
+}
+
+// schemaGenerator generates schemas for Go types.
+type schemaGenerator struct {
+	tags tagNames
 }
 
-func makeSchema(valueType reflect.Type, tag reflect.StructTag) (*Schema, error) {
+func (g schemaGenerator) generate(value any) (*Schema, error) {
+	schema, err := g.makeSchema(reflect.TypeOf(value), "")
+	if err != nil {
+		return nil, err
+	}
+
+	schema.Dialect = Draft202012
+	return schema, nil
+}
+
+func (g schemaGenerator) makeSchema(valueType reflect.Type, tag reflect.StructTag) (*Schema, error) {
 	resolvedType := indirectType(valueType)
 	value := reflect.New(resolvedType).Elem()
 	sourceValue := value
@@ -140,13 +311,37 @@ func makeSchema(valueType reflect.Type, tag reflect.StructTag) (*Schema, error)
 		sourceValue = sourceValue.Addr()
 	}
 
+	if secret, ok := value.Interface().(secret); ok {
+		// secrets are described by schema of the wrapped type, but their values are never exposed
+		s, err := g.makeSchema(secret.secretType(), "")
+		if err != nil {
+			return nil, errors.Wrap(err, "generate secret")
+		}
+
+		s.WriteOnly = true
+		if err := g.applySchemaProps(s, tag, valueType, nil); err != nil {
+			return nil, errors.Wrap(err, "apply props")
+		}
+
+		return s, nil
+	}
+
+	s := Schema{tags: g.tags}
+	if isFreeForm(resolvedType) {
+		// any value is allowed, so the schema is left empty
+		if err := g.applySchemaProps(&s, tag, valueType, nil); err != nil {
+			return nil, errors.Wrap(err, "apply props")
+		}
+
+		return &s, nil
+	}
+
 	var node yaml.Node
 	if err := node.Encode(sourceValue.Interface()); err != nil {
 		return nil, errors.Wrap(err, "encode")
 	}
 
 	var elemType reflect.Type
-	var s Schema
 	switch {
 	case node.Tag == "!!str":
 		s.Type = "string"
@@ -188,7 +383,7 @@ func makeSchema(valueType reflect.Type, tag reflect.StructTag) (*Schema, error)
 
 	case node.Tag == "!!seq" && (resolvedType.Kind() == reflect.Slice || resolvedType.Kind() == reflect.Array):
 		elemType = valueType.Elem()
-		items, err := makeSchema(elemType, "")
+		items, err := g.makeSchema(elemType, "")
 		if err != nil {
 			return nil, errors.Wrap(err, "generate items")
 		}
@@ -202,16 +397,20 @@ func makeSchema(valueType reflect.Type, tag reflect.StructTag) (*Schema, error)
 
 	case node.Tag == "!!map" && resolvedType.Kind() == reflect.Map:
 		elemType = resolvedType.Elem()
-		additionalProperties, err := makeSchema(elemType, "")
+		additionalProperties, err := g.makeSchema(elemType, "")
 		if err != nil {
 			return nil, errors.Wrap(err, "generate additionalProperties")
 		}
 
 		s.Type = "object"
-		s.AdditionalProperties = additionalProperties
+		if isFreeForm(indirectType(elemType)) {
+			s.AdditionalProperties = true
+		} else {
+			s.AdditionalProperties = additionalProperties
+		}
 
 	case node.Tag == "!!map" && resolvedType.Kind() == reflect.Struct:
-		properties, required, err := makeStructSchema(valueType)
+		properties, required, err := g.makeStructSchema(valueType)
 		if err != nil {
 			return nil, errors.Wrap(err, "generate properties & required")
 		}
@@ -226,14 +425,14 @@ func makeSchema(valueType reflect.Type, tag reflect.StructTag) (*Schema, error)
 		return nil, errors.Errorf("unable to detect type for %s %s", node.Tag, resolvedType)
 	}
 
-	if err := applySchemaProps(&s, tag, valueType, elemType); err != nil {
+	if err := g.applySchemaProps(&s, tag, valueType, elemType); err != nil {
 		return nil, errors.Wrap(err, "apply props")
 	}
 
 	return &s, nil
 }
 
-func applySchemaProps(s *Schema, tag reflect.StructTag, valueType, elemType reflect.Type) error {
+func (g schemaGenerator) applySchemaProps(s *Schema, tag reflect.StructTag, valueType, elemType reflect.Type) error {
 	schema := reflect.ValueOf(s).Elem()
 	schemaType := schema.Type()
 	for fieldNum := 0; fieldNum < schemaType.NumField(); fieldNum++ {
@@ -256,7 +455,7 @@ func applySchemaProps(s *Schema, tag reflect.StructTag, valueType, elemType refl
 			continue
 		}
 
-		aliases := []string{getYAMLOptions(field).name}
+		aliases := []string{strings.Split(field.Tag.Get("yaml"), ",")[0]}
 		aliases = append(aliases, strings.Split(field.Tag.Get("alias"), ",")...)
 
 		var prop string
@@ -271,6 +470,11 @@ func applySchemaProps(s *Schema, tag reflect.StructTag, valueType, elemType refl
 			continue
 		}
 
+		if field.Name == "Default" && isTemplate(prop) {
+			s.defaultTemplate = prop
+			continue
+		}
+
 		targetField := schema.Field(fieldNum)
 		var fieldValue reflect.Value
 		if field.Type.String() == "interface {}" {
@@ -279,8 +483,8 @@ func applySchemaProps(s *Schema, tag reflect.StructTag, valueType, elemType refl
 				targetType = elemType
 				if s.Type == "array" {
 					targetField = reflect.Indirect(reflect.ValueOf(s.Items)).Field(fieldNum)
-				} else if s.Type == "object" && s.AdditionalProperties != false {
-					targetField = reflect.Indirect(reflect.ValueOf(s.AdditionalProperties)).Field(fieldNum)
+				} else if additionalProperties, ok := s.AdditionalProperties.(*Schema); ok && s.Type == "object" {
+					targetField = reflect.ValueOf(additionalProperties).Elem().Field(fieldNum)
 				}
 			}
 
@@ -294,7 +498,7 @@ func applySchemaProps(s *Schema, tag reflect.StructTag, valueType, elemType refl
 			fieldValue = reflect.New(field.Type)
 		}
 
-		if err := yaml.Unmarshal([]byte(prop), fieldValue.Interface()); err != nil {
+		if err := g.tags.unmarshalYAML([]byte(prop), fieldValue.Interface()); err != nil {
 			return errors.Wrapf(err, "unmarshal %s", prop)
 		}
 
@@ -305,29 +509,29 @@ func applySchemaProps(s *Schema, tag reflect.StructTag, valueType, elemType refl
 	return nil
 }
 
-func makeStructSchema(valueType reflect.Type) (map[string]Schema, []string, error) {
+func (g schemaGenerator) makeStructSchema(valueType reflect.Type) (*OrderedMap[Schema], []string, error) {
 	var (
 		resolvedType = indirectType(valueType)
-		properties   = make(map[string]Schema)
+		properties   = new(OrderedMap[Schema])
 		required     []string
 	)
 
 	for fieldNum := 0; fieldNum < resolvedType.NumField(); fieldNum++ {
 		field := resolvedType.Field(fieldNum)
-		if !field.IsExported() {
+		options := g.tags.fieldOptions(field)
+		if options.skip {
 			continue
 		}
 
-		options := getYAMLOptions(field)
 		if options.inline {
-			embedded, err := makeSchema(field.Type, "")
+			embedded, err := g.makeSchema(field.Type, "")
 			if err != nil {
 				return nil, nil, errors.Wrapf(err, "generate embedded schema for %s", options.name)
 			}
 
 			required = append(required, embedded.Required...)
-			for name, property := range embedded.Properties {
-				properties[name] = property
+			for _, property := range embedded.Properties.Entries() {
+				properties.Set(property.Key, property.Value)
 			}
 
 			continue
@@ -337,32 +541,63 @@ func makeStructSchema(valueType reflect.Type) (map[string]Schema, []string, erro
 			required = append(required, options.name)
 		}
 
-		property, err := makeSchema(resolvedType.Field(fieldNum).Type, field.Tag)
+		property, err := g.makeSchema(resolvedType.Field(fieldNum).Type, field.Tag)
 		if err != nil {
 			return nil, nil, errors.Wrapf(err, "generate schema for %s", options.name)
 		}
 
-		properties[options.name] = *property
+		properties.Set(options.name, *property)
 	}
 
 	return properties, required, nil
 }
 
-type yamlOptions struct {
+// tagNames lists struct tags which are used to read property names and options of struct fields
+// in schema generation, decoding and encoding. The first tag present on a field wins.
+// The default chain (yaml, json) is used if it is empty.
+type tagNames []string
+
+var defaultTagNames = tagNames{"yaml", "json"}
+
+// WithTagNames sets struct tags which are used to read property names and options of struct fields
+// (yaml and json by default). The first tag present on a field wins,
+// so the first name is the primary tag and the rest form a fallback chain,
+// e.g. WithTagNames("confi", "yaml", "json") prefers a custom tag.
+func WithTagNames(names ...string) Option {
+	return func(options *options) {
+		options.tagNames = names
+	}
+}
+
+type fieldOptions struct {
 	name      string
+	skip      bool
 	omitempty bool
 	inline    bool
 }
 
-func getYAMLOptions(field reflect.StructField) (options yamlOptions) {
-	for i, option := range strings.Split(field.Tag.Get("yaml"), ",") {
+func (t tagNames) fieldOptions(field reflect.StructField) (options fieldOptions) {
+	if len(t) == 0 {
+		t = defaultTagNames
+	}
+
+	var tagName, tag string
+	for _, name := range t {
+		if value, ok := field.Tag.Lookup(name); ok {
+			tagName, tag = name, value
+			break
+		}
+	}
+
+	if tag == "-" || !field.IsExported() {
+		options.skip = true
+		return
+	}
+
+	for i, option := range strings.Split(tag, ",") {
 		switch {
 		case i == 0:
-			if len(option) > 0 {
-				options.name = option
-			} else {
-				options.name = field.Name
-			}
+			options.name = option
 
 		case option == "omitempty":
 			options.omitempty = true
@@ -372,9 +607,27 @@ func getYAMLOptions(field reflect.StructField) (options yamlOptions) {
 		}
 	}
 
+	if options.name == "" {
+		if tagName == "json" {
+			// names default to field names and embedded structs are inlined like encoding/json does
+			options.name = field.Name
+			options.inline = options.inline || field.Anonymous && indirectType(field.Type).Kind() == reflect.Struct
+		} else {
+			// names default to lowercase field names like yaml.v3 does
+			options.name = strings.ToLower(field.Name)
+		}
+	}
+
 	return
 }
 
+var nodeType = reflect.TypeOf(yaml.Node{})
+
+// isFreeForm reports whether values of the type may hold arbitrary data.
+func isFreeForm(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Interface || typ == nodeType
+}
+
 func indirectType(typ reflect.Type) reflect.Type {
 	if typ.Kind() == reflect.Ptr {
 		typ = typ.Elem()
@@ -1,12 +1,17 @@
 package confi_test
 
 import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/AlekSi/pointer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 
 	"github.com/jfk9w-go/confi"
 )
@@ -24,6 +29,10 @@ func TestGenerateSchema(t *testing.T) {
 		String string `yaml:"string,omitempty" default:"default_value"`
 	}
 
+	type JSONInnerObj struct {
+		Number int `json:"number,omitempty"`
+	}
+
 	tests := []struct {
 		name     string
 		value    any
@@ -43,25 +52,26 @@ func TestGenerateSchema(t *testing.T) {
 				unexported string
 			}{},
 			expected: confi.Schema{
+				Dialect:              confi.Draft202012,
 				Type:                 "object",
 				Required:             []string{"string"},
 				AdditionalProperties: false,
-				Properties: map[string]confi.Schema{
-					"string": {
+				Properties: confi.NewOrderedMap([]confi.Entry[confi.Schema]{
+					{Key: "string", Value: confi.Schema{
 						Type:      "string",
 						Default:   "value1",
 						Enum:      []string{"value1", "value2", "value3"},
 						MinLength: 6,
 						MaxLength: pointer.To(uint64(6)),
-					},
-					"float": {
+					}},
+					{Key: "float", Value: confi.Schema{
 						Type:    "number",
 						Default: float64(15),
 						Enum:    []float64{14, 15, 16},
 						Minimum: float64(14),
 						Maximum: float64(16),
-					},
-					"integer": {
+					}},
+					{Key: "integer", Value: confi.Schema{
 						Type:    "integer",
 						Default: pointer.To(10),
 						Enum: []*int{
@@ -71,30 +81,30 @@ func TestGenerateSchema(t *testing.T) {
 						},
 						ExclusiveMinimum: pointer.To(9),
 						ExclusiveMaximum: pointer.To(13),
-					},
-					"bool": {
+					}},
+					{Key: "bool", Value: confi.Schema{
 						Type:        "boolean",
 						Description: "Boolean field",
 						Examples:    []bool{true, false},
 						Default:     true,
-					},
-					"time": {
+					}},
+					{Key: "time", Value: confi.Schema{
 						Type:   "string",
 						Format: "date-time",
-					},
-					"duration": {
+					}},
+					{Key: "duration", Value: confi.Schema{
 						Type:    "string",
 						Pattern: `(\d+h)?(\d+m)?(\d+s)?(\d+ms)?(\d+µs)?(\d+ns)?`,
-					},
-					"patterned": {
+					}},
+					{Key: "patterned", Value: confi.Schema{
 						Type:    "string",
 						Pattern: patternedValue("").SchemaPattern(),
-					},
-					"formatted": {
+					}},
+					{Key: "formatted", Value: confi.Schema{
 						Type:   "string",
 						Format: formattedValue("").SchemaFormat(),
-					},
-				},
+					}},
+				}...),
 			},
 		},
 		{
@@ -108,30 +118,31 @@ func TestGenerateSchema(t *testing.T) {
 				Map      map[string]*InnerObj `yaml:"map,omitempty" default:"{eee: {string: ggg}}" enum:"{string: eee}, {string: ggg}" minprops:"1" maxprops:"3"`
 			}),
 			expected: confi.Schema{
+				Dialect:              confi.Draft202012,
 				Type:                 "object",
 				Required:             []string{"inner"},
 				AdditionalProperties: false,
-				Properties: map[string]confi.Schema{
-					"string": {Type: "string", Default: "default_value"},
-					"inner": {
+				Properties: confi.NewOrderedMap([]confi.Entry[confi.Schema]{
+					{Key: "string", Value: confi.Schema{Type: "string", Default: "default_value"}},
+					{Key: "inner", Value: confi.Schema{
 						Type:                 "object",
 						AdditionalProperties: false,
-						Properties: map[string]confi.Schema{
-							"string": {Type: "string", Default: "default_value"},
-						},
+						Properties: confi.NewOrderedMap([]confi.Entry[confi.Schema]{
+							{Key: "string", Value: confi.Schema{Type: "string", Default: "default_value"}},
+						}...),
 						Default: InnerObj{String: "aaa"},
 						Enum:    []InnerObj{{String: "aaa"}, {String: "bbb"}},
-					},
-					"innerPtr": {
+					}},
+					{Key: "innerPtr", Value: confi.Schema{
 						Type:                 "object",
 						AdditionalProperties: false,
-						Properties: map[string]confi.Schema{
-							"string": {Type: "string", Default: "default_value"},
-						},
+						Properties: confi.NewOrderedMap([]confi.Entry[confi.Schema]{
+							{Key: "string", Value: confi.Schema{Type: "string", Default: "default_value"}},
+						}...),
 						Default:  &InnerObj{String: "bbb"},
 						Examples: []*InnerObj{{String: "bbb"}, {String: "ccc"}},
-					},
-					"slice": {
+					}},
+					{Key: "slice", Value: confi.Schema{
 						Type:     "array",
 						MinItems: 1,
 						MaxItems: pointer.To(uint64(3)),
@@ -139,13 +150,13 @@ func TestGenerateSchema(t *testing.T) {
 						Items: &confi.Schema{
 							Type:                 "object",
 							AdditionalProperties: false,
-							Properties: map[string]confi.Schema{
-								"string": {Type: "string", Default: "default_value"},
-							},
+							Properties: confi.NewOrderedMap([]confi.Entry[confi.Schema]{
+								{Key: "string", Value: confi.Schema{Type: "string", Default: "default_value"}},
+							}...),
 							Enum: []*InnerObj{{String: "ccc"}, {String: "ddd"}},
 						},
-					},
-					"array": {
+					}},
+					{Key: "array", Value: confi.Schema{
 						Type:        "array",
 						MinItems:    1,
 						MaxItems:    pointer.To(uint64(1)),
@@ -154,13 +165,13 @@ func TestGenerateSchema(t *testing.T) {
 						Items: &confi.Schema{
 							Type:                 "object",
 							AdditionalProperties: false,
-							Properties: map[string]confi.Schema{
-								"string": {Type: "string", Default: "default_value"},
-							},
+							Properties: confi.NewOrderedMap([]confi.Entry[confi.Schema]{
+								{Key: "string", Value: confi.Schema{Type: "string", Default: "default_value"}},
+							}...),
 							Examples: []InnerObj{{String: "ddd"}, {String: "eee"}},
 						},
-					},
-					"map": {
+					}},
+					{Key: "map", Value: confi.Schema{
 						Type:          "object",
 						MinProperties: 1,
 						MaxProperties: pointer.To(uint64(3)),
@@ -168,13 +179,66 @@ func TestGenerateSchema(t *testing.T) {
 						AdditionalProperties: &confi.Schema{
 							Type:                 "object",
 							AdditionalProperties: false,
-							Properties: map[string]confi.Schema{
-								"string": {Type: "string", Default: "default_value"},
-							},
+							Properties: confi.NewOrderedMap([]confi.Entry[confi.Schema]{
+								{Key: "string", Value: confi.Schema{Type: "string", Default: "default_value"}},
+							}...),
 							Enum: []*InnerObj{{String: "eee"}, {String: "ggg"}},
 						},
-					},
-				},
+					}},
+				}...),
+			},
+		},
+		{
+			name: "tags",
+			value: struct {
+				YAML    string `yaml:"yaml_name" json:"ignored"`
+				JSON    string `json:"json_name,omitempty"`
+				Skipped string `yaml:"-"`
+				Plain   string
+				InnerObj
+				JSONInnerObj `json:",omitempty"`
+			}{},
+			expected: confi.Schema{
+				Dialect:              confi.Draft202012,
+				Type:                 "object",
+				Required:             []string{"yaml_name", "plain", "innerobj"},
+				AdditionalProperties: false,
+				Properties: confi.NewOrderedMap([]confi.Entry[confi.Schema]{
+					{Key: "yaml_name", Value: confi.Schema{Type: "string"}},
+					{Key: "json_name", Value: confi.Schema{Type: "string"}},
+					{Key: "plain", Value: confi.Schema{Type: "string"}},
+					{Key: "innerobj", Value: confi.Schema{
+						Type:                 "object",
+						AdditionalProperties: false,
+						Properties: confi.NewOrderedMap([]confi.Entry[confi.Schema]{
+							{Key: "string", Value: confi.Schema{Type: "string", Default: "default_value"}},
+						}...),
+					}},
+					{Key: "number", Value: confi.Schema{Type: "integer"}},
+				}...),
+			},
+		},
+		{
+			name: "free form",
+			value: struct {
+				Any     any            `yaml:"any" default:"{key: value}"`
+				Map     map[string]any `yaml:"map,omitempty"`
+				Slice   []any          `yaml:"slice,omitempty"`
+				Node    yaml.Node      `yaml:"node,omitempty" doc:"Raw node"`
+				NodePtr *yaml.Node     `yaml:"nodePtr,omitempty"`
+			}{},
+			expected: confi.Schema{
+				Dialect:              confi.Draft202012,
+				Type:                 "object",
+				Required:             []string{"any"},
+				AdditionalProperties: false,
+				Properties: confi.NewOrderedMap([]confi.Entry[confi.Schema]{
+					{Key: "any", Value: confi.Schema{Default: map[string]any{"key": "value"}}},
+					{Key: "map", Value: confi.Schema{Type: "object", AdditionalProperties: true}},
+					{Key: "slice", Value: confi.Schema{Type: "array", Items: &confi.Schema{}}},
+					{Key: "node", Value: confi.Schema{Description: "Raw node"}},
+					{Key: "nodePtr", Value: confi.Schema{}},
+				}...),
 			},
 		},
 	}
@@ -188,11 +252,75 @@ func TestGenerateSchema(t *testing.T) {
 	}
 }
 
+func TestSchema_MarshalJSON(t *testing.T) {
+	type Value struct {
+		Name     string `yaml:"name" title:"Name" default:"app" const:"app" comment:"fixed"`
+		Password string `yaml:"password,omitempty" writeonly:"true"`
+		Version  string `yaml:"version,omitempty" readonly:"true"`
+		Legacy   bool   `yaml:"legacy,omitempty" deprecated:"true"`
+	}
+
+	schema, err := confi.GenerateSchema(Value{})
+	require.NoError(t, err)
+
+	data, err := json.Marshal(schema)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "title": "Name", "$comment": "fixed", "default": "app", "const": "app"},
+			"password": {"type": "string", "writeOnly": true},
+			"version": {"type": "string", "readOnly": true},
+			"legacy": {"type": "boolean", "deprecated": true}
+		},
+		"additionalProperties": false,
+		"required": ["name"]
+	}`, string(data))
+}
+
+// TestSchema_TestSuiteRoundTrip checks that schemas of the JSON-Schema-Test-Suite draft 2020-12 cases
+// stored in testdata survive JSON unmarshalling and marshalling unchanged, i.e. that all their keywords
+// are supported by Schema and serialized with standard names. Instances (tests[].data) are not validated,
+// since Schema does not implement validation.
+func TestSchema_TestSuiteRoundTrip(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "draft2020-12", "*.json"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+
+		var cases []struct {
+			Description string          `json:"description"`
+			Schema      json.RawMessage `json:"schema"`
+		}
+
+		require.NoError(t, json.Unmarshal(data, &cases))
+		for _, tt := range cases {
+			t.Run(filepath.Base(file)+"/"+tt.Description, func(t *testing.T) {
+				var schema confi.Schema
+				require.NoError(t, json.Unmarshal(tt.Schema, &schema))
+
+				actual, err := json.Marshal(schema)
+				require.NoError(t, err)
+				assert.JSONEq(t, string(tt.Schema), string(actual))
+			})
+		}
+	}
+}
+
 func TestSchema_ApplyDefaults(t *testing.T) {
 	type InnerObj struct {
 		InnerString string `yaml:"innerString" default:"default_inner_string"`
 	}
 
+	type Upstream struct {
+		Address string        `yaml:"address"`
+		Timeout time.Duration `yaml:"timeout" default:"5s"`
+	}
+
 	type Value struct {
 		EmbeddedObj          InnerObj             `yaml:",inline"`
 		String               string               `yaml:"string" default:"default_string"`
@@ -210,6 +338,8 @@ func TestSchema_ApplyDefaults(t *testing.T) {
 		FilledInnerObjPtr    *InnerObj            `yaml:"filledInnerObjPtr" default:"{innerString: default_inner_string}"`
 		Map                  map[string]InnerObj  `yaml:"map" default:"{aaa: {innerString: bbb}}"`
 		HalfSetMap           map[string]*InnerObj `yaml:"halfSetMap"`
+		ValueMap             map[string]Upstream  `yaml:"valueMap"`
+		Array                [2]Upstream          `yaml:"array"`
 	}
 
 	value := Value{
@@ -226,6 +356,11 @@ func TestSchema_ApplyDefaults(t *testing.T) {
 			"ccc": {},
 			"ddd": nil, // this will be ignored
 		},
+		ValueMap: map[string]Upstream{
+			"aaa": {Address: "a:80"},
+			"bbb": {Address: "b:80", Timeout: time.Second},
+		},
+		Array: [2]Upstream{{Address: "c:80"}},
 	}
 
 	schema, err := confi.GenerateSchema(new(Value))
@@ -248,5 +383,86 @@ func TestSchema_ApplyDefaults(t *testing.T) {
 		FilledInnerObjPtr:    pointer.To(InnerObj{InnerString: "default_inner_string"}),
 		Map:                  map[string]InnerObj{"aaa": {InnerString: "bbb"}},
 		HalfSetMap:           map[string]*InnerObj{"aaa": {InnerString: "bbb"}, "ccc": {InnerString: "default_inner_string"}, "ddd": nil},
+		ValueMap: map[string]Upstream{
+			"aaa": {Address: "a:80", Timeout: 5 * time.Second},
+			"bbb": {Address: "b:80", Timeout: time.Second},
+		},
+		Array: [2]Upstream{{Address: "c:80", Timeout: 5 * time.Second}, {Timeout: 5 * time.Second}},
 	}, value)
 }
+
+type defaultedNode struct {
+	ID   string `yaml:"id"`
+	Port int    `yaml:"port" default:"8080"`
+}
+
+func (n *defaultedNode) SetDefaults() {
+	n.ID = "node-" + strconv.Itoa(n.Port)
+	n.Port = 9000
+}
+
+func TestSchema_ApplyDefaults_Computed(t *testing.T) {
+	t.Setenv("CONFI_TEST_HOSTNAME", "host")
+
+	type HTTP struct {
+		Host string `yaml:"host" default:"0.0.0.0"`
+		Port int    `yaml:"port" default:"80"`
+	}
+
+	type Metrics struct {
+		Addr    string  `yaml:"addr" default:"${ref:http.host}:9090"`
+		Port    *int    `yaml:"port" default:"${ref:http.port}"`
+		Escaped string  `yaml:"escaped" default:"$${ref:http.host}"`
+		Node    *string `yaml:"node" default:"${env:CONFI_TEST_HOSTNAME}-${env:CONFI_TEST_MISSING:-x}"`
+	}
+
+	type Value struct {
+		Metrics Metrics       `yaml:"metrics"`
+		HTTP    HTTP          `yaml:"http"`
+		Node    defaultedNode `yaml:"node"`
+		Label   string        `yaml:"label" default:"${ref:metrics.addr}/${ref:node.id}"`
+	}
+
+	schema, err := confi.GenerateSchema(new(Value))
+	require.NoError(t, err)
+
+	var value Value
+	require.NoError(t, schema.ApplyDefaults(&value))
+	assert.Equal(t, Value{
+		Metrics: Metrics{
+			Addr:    "0.0.0.0:9090",
+			Port:    pointer.To(80),
+			Escaped: "${ref:http.host}",
+			Node:    pointer.To("host-x"),
+		},
+		HTTP:  HTTP{Host: "0.0.0.0", Port: 80},
+		Node:  defaultedNode{ID: "node-0", Port: 9000},
+		Label: "0.0.0.0:9090/node-0",
+	}, value)
+
+	value = Value{HTTP: HTTP{Host: "example.com"}, Metrics: Metrics{Addr: "custom"}}
+	require.NoError(t, schema.ApplyDefaults(&value))
+	assert.Equal(t, "custom", value.Metrics.Addr)
+	assert.Equal(t, "custom/node-0", value.Label)
+
+	type Cycle struct {
+		A string `yaml:"a" default:"${ref:b}"`
+		B string `yaml:"b" default:"${ref:a}"`
+	}
+
+	schema, err = confi.GenerateSchema(new(Cycle))
+	require.NoError(t, err)
+	err = schema.ApplyDefaults(new(Cycle))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "reference cycle: b -> a -> b")
+
+	type Literal struct {
+		Path string `yaml:"path" default:"${HOME}/x"`
+	}
+
+	schema, err = confi.GenerateSchema(new(Literal))
+	require.NoError(t, err)
+	literal := new(Literal)
+	require.NoError(t, schema.ApplyDefaults(literal))
+	assert.Equal(t, "${HOME}/x", literal.Path)
+}
@@ -0,0 +1,76 @@
+package confi
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Redacted replaces secret values in any output.
+const Redacted = "[REDACTED]"
+
+// Secret holds a sensitive configuration value.
+// It is decoded as T, but redacted when formatted, marshaled, logged or used in schema.
+// The only way to read the value is Reveal.
+type Secret[T any] struct {
+	value T
+}
+
+// NewSecret wraps value into Secret.
+func NewSecret[T any](value T) Secret[T] {
+	return Secret[T]{value: value}
+}
+
+// Reveal returns the secret value.
+func (s Secret[T]) Reveal() T {
+	return s.value
+}
+
+func (s Secret[T]) String() string   { return Redacted }
+func (s Secret[T]) GoString() string { return "confi.Secret{" + Redacted + "}" }
+
+// Format prevents fmt from printing the value with verbs which do not use String method.
+func (s Secret[T]) Format(state fmt.State, verb rune) {
+	if verb == 'v' && state.Flag('#') {
+		_, _ = fmt.Fprint(state, s.GoString())
+		return
+	}
+
+	_, _ = fmt.Fprint(state, Redacted)
+}
+
+func (s Secret[T]) LogValue() slog.Value { return slog.StringValue(Redacted) }
+
+func (s Secret[T]) MarshalYAML() (any, error) { return Redacted, nil }
+
+func (s *Secret[T]) UnmarshalYAML(node *yaml.Node) error {
+	return s.decodeSecret(nil, node)
+}
+
+func (s Secret[T]) MarshalJSON() ([]byte, error) { return json.Marshal(Redacted) }
+
+func (s *Secret[T]) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &s.value)
+}
+
+func (s Secret[T]) secretType() reflect.Type { return reflect.TypeOf((*T)(nil)).Elem() }
+func (s Secret[T]) secretValue() any         { return s.value }
+
+func (s *Secret[T]) decodeSecret(tags tagNames, node *yaml.Node) error {
+	return tags.decodeNode(node, reflect.ValueOf(&s.value).Elem())
+}
+
+// secret is implemented by Secret values of any type.
+type secret interface {
+	secretType() reflect.Type
+	secretValue() any
+}
+
+// secretDecoder is implemented by Secret pointers of any type.
+// It allows decoding the wrapped value with struct tags used by the caller.
+type secretDecoder interface {
+	decodeSecret(tags tagNames, node *yaml.Node) error
+}
@@ -0,0 +1,204 @@
+package confi
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SecretPolicy defines how secret (write-only) properties supplied in an unsafe way are handled.
+type SecretPolicy int
+
+const (
+	// SecretPolicyIgnore does not check how secrets are supplied.
+	SecretPolicyIgnore SecretPolicy = iota
+	// SecretPolicyWarn reports unsafe secrets as warnings (see WithWarningHandler).
+	SecretPolicyWarn
+	// SecretPolicyRefuse fails the load when secrets are supplied in an unsafe way.
+	SecretPolicyRefuse
+)
+
+// WithSecretPolicy enables checks of secret properties: secrets must not be passed as command-line options
+// (which are visible to other users via ps) and files setting secrets must not be readable by group or others.
+// Command-line options are checked by DefaultSourceProvider, which requires its Schema to be set (Get sets it).
+func WithSecretPolicy(policy SecretPolicy) Option {
+	return func(options *options) {
+		options.secretPolicy = policy
+	}
+}
+
+type secretPolicyKey struct{}
+
+// SecretPolicyFromContext returns the policy set by WithSecretPolicy for the load ctx belongs to.
+// It may be used by custom SourceProvider implementations with ctx passed to them.
+func SecretPolicyFromContext(ctx context.Context) SecretPolicy {
+	policy, _ := ctx.Value(secretPolicyKey{}).(SecretPolicy)
+	return policy
+}
+
+func withSecretPolicy(ctx context.Context, policy SecretPolicy) context.Context {
+	if policy == SecretPolicyIgnore {
+		return ctx
+	}
+
+	return context.WithValue(ctx, secretPolicyKey{}, policy)
+}
+
+// report handles warning according to policy.
+func (p SecretPolicy) report(ctx context.Context, warning Warning) error {
+	switch p {
+	case SecretPolicyWarn:
+		Warn(ctx, warning)
+	case SecretPolicyRefuse:
+		return errors.New(warning.String())
+	}
+
+	return nil
+}
+
+// checkArgs checks that no secret properties are set in props.
+func (p SecretPolicy) checkArgs(ctx context.Context, props []Property, schema *Schema) error {
+	if p == SecretPolicyIgnore || schema == nil {
+		return nil
+	}
+
+	for _, prop := range props {
+		if IsEncrypted(prop.Value) {
+			continue
+		}
+
+		if last := len(prop.Path) - 1; last > 0 && strings.EqualFold(prop.Path[last], "file") {
+			if property := schema.Lookup(prop.Path[:last]...); property != nil && property.AllowFile {
+				continue
+			}
+		}
+
+		for i := range prop.Path {
+			if property := schema.Lookup(prop.Path[:i+1]...); property != nil && property.WriteOnly {
+				if err := p.report(ctx, Warning{
+					Code:    WarnSecretInArgs,
+					Source:  "args",
+					Path:    prop.Key(),
+					Message: "secret passed as command-line option is visible to other users",
+				}); err != nil {
+					return err
+				}
+
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkSource checks that source setting secret properties in plain text is not readable by group or others.
+// values must be read from source before decryption.
+func (p SecretPolicy) checkSource(ctx context.Context, source Source, values map[string]any, schema *Schema) error {
+	if p == SecretPolicyIgnore {
+		return nil
+	}
+
+	input, ok := source.(InputSource)
+	if !ok {
+		return nil
+	}
+
+	file, ok := input.Input.(File)
+	if !ok {
+		return nil
+	}
+
+	paths := secretPaths(values, schema, nil)
+	if len(paths) == 0 {
+		return nil
+	}
+
+	info, err := os.Stat(file.Path())
+	if err != nil {
+		return errors.Wrapf(err, "stat %s", file.Path())
+	}
+
+	if info.Mode().Perm()&0o044 == 0 {
+		return nil
+	}
+
+	for _, path := range paths {
+		if err := p.report(ctx, Warning{
+			Code:    WarnSecretFilePermission,
+			Source:  file.Path(),
+			Path:    path,
+			Message: "file setting secrets is readable by group or others (mode " + info.Mode().Perm().String() + ")",
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// secretPaths returns sorted paths of secret properties set in value in plain text.
+func secretPaths(value any, schema *Schema, path []string) []string {
+	if schema == nil {
+		return nil
+	}
+
+	if schema.WriteOnly {
+		if !hasPlainText(value) {
+			return nil
+		}
+
+		return []string{strings.Join(path, ".")}
+	}
+
+	var paths []string
+	switch value := value.(type) {
+	case map[string]any:
+		for key, value := range value {
+			paths = append(paths, secretPaths(value, schema.Lookup(key), append(path[:len(path):len(path)], key))...)
+		}
+
+	case []any:
+		for i, value := range value {
+			paths = append(paths, secretPaths(value, schema.Items, append(path[:len(path):len(path)], strconv.Itoa(i)))...)
+		}
+	}
+
+	sort.Strings(paths)
+	return paths
+}
+
+// hasPlainText reports whether value contains any scalars which are not encrypted.
+func hasPlainText(value any) bool {
+	switch value := value.(type) {
+	case map[string]any:
+		for _, item := range value {
+			if hasPlainText(item) {
+				return true
+			}
+		}
+
+		return false
+
+	case []any:
+		for _, item := range value {
+			if hasPlainText(item) {
+				return true
+			}
+		}
+
+		return false
+
+	case string:
+		return !IsEncrypted(value)
+
+	case nil:
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,130 @@
+package confi_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jfk9w-go/confi"
+)
+
+func TestWithSecretPolicy(t *testing.T) {
+	type Config struct {
+		User     string                          `yaml:"user"`
+		Password confi.Secret[string]            `yaml:"password" file:"true"`
+		Tokens   map[string]confi.Secret[string] `yaml:"tokens,omitempty"`
+	}
+
+	schema, err := confi.GenerateSchema(Config{})
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	write := func(name, content string, mode os.FileMode) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), mode))
+		require.NoError(t, os.Chmod(path, mode))
+		return path
+	}
+
+	private := write("private.yaml", "password: secret", 0o600)
+	public := write("public.yaml", "password: secret\ntokens: {a: token}", 0o644)
+	plain := write("plain.yaml", "user: admin", 0o644)
+	passwordFile := write("password", "secret", 0o600)
+
+	key, err := confi.GenerateKey()
+	require.NoError(t, err)
+	cipher, err := confi.NewCipher(key)
+	require.NoError(t, err)
+	encryptedPassword, err := cipher.Encrypt("secret")
+	require.NoError(t, err)
+	encryptedToken, err := cipher.Encrypt("token")
+	require.NoError(t, err)
+	keyFile := write("key", key, 0o600)
+	encrypted := write("encrypted.yaml", "password: "+encryptedPassword+"\ntokens:\n  a: "+encryptedToken, 0o644)
+
+	tests := []struct {
+		name     string
+		args     []string
+		policy   confi.SecretPolicy
+		warnings []confi.Warning
+		error    string
+	}{
+		{
+			name:   "ignore",
+			args:   []string{"--password=secret", "--config.file=" + public},
+			policy: confi.SecretPolicyIgnore,
+		},
+		{
+			name:   "safe",
+			args:   []string{"--user=admin", "--password.file=" + passwordFile, "--config.file=" + private, "--config.file=" + plain},
+			policy: confi.SecretPolicyRefuse,
+		},
+		{
+			name:   "encrypted",
+			args:   []string{"--config.key.file=" + keyFile, "--config.file=" + encrypted},
+			policy: confi.SecretPolicyRefuse,
+		},
+		{
+			name:   "warn",
+			args:   []string{"--password=secret", "--user=admin", "--config.file=" + public},
+			policy: confi.SecretPolicyWarn,
+			warnings: []confi.Warning{
+				{
+					Code:    confi.WarnSecretInArgs,
+					Source:  "args",
+					Path:    "password",
+					Message: "secret passed as command-line option is visible to other users",
+				},
+				{
+					Code:    confi.WarnSecretFilePermission,
+					Source:  public,
+					Path:    "password",
+					Message: "file setting secrets is readable by group or others (mode -rw-r--r--)",
+				},
+				{
+					Code:    confi.WarnSecretFilePermission,
+					Source:  public,
+					Path:    "tokens.a",
+					Message: "file setting secrets is readable by group or others (mode -rw-r--r--)",
+				},
+			},
+		},
+		{
+			name:   "refuse args",
+			args:   []string{"--tokens.a=token"},
+			policy: confi.SecretPolicyRefuse,
+			error:  "get sources: check secrets: args: on tokens.a: secret passed as command-line option is visible to other users",
+		},
+		{
+			name:   "refuse file",
+			args:   []string{"--config.file=" + public},
+			policy: confi.SecretPolicyRefuse,
+			error:  "check secrets: " + public + ": on password: file setting secrets is readable by group or others (mode -rw-r--r--)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var warnings []confi.Warning
+			_, _, err := confi.FromProvider[Config](context.Background(),
+				&confi.DefaultSourceProvider{
+					Args:   tt.args,
+					Schema: schema,
+				},
+				confi.WithSecretPolicy(tt.policy),
+				confi.WithWarningHandler(func(warning confi.Warning) { warnings = append(warnings, warning) }))
+
+			if tt.error != "" {
+				require.EqualError(t, err, tt.error)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.warnings, warnings)
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package confi
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultSecretTimeout is the default timeout of a single SecretResolver call.
+const DefaultSecretTimeout = 10 * time.Second
+
+// SecretResolver resolves values referenced by URIs like secret://vault/kv/app#password.
+// Resolvers are registered per URI scheme with WithSecretResolver option.
+type SecretResolver interface {
+	ResolveSecret(ctx context.Context, uri *url.URL) (string, error)
+}
+
+// SecretResolverFunc is a function implementing SecretResolver.
+type SecretResolverFunc func(ctx context.Context, uri *url.URL) (string, error)
+
+func (fn SecretResolverFunc) ResolveSecret(ctx context.Context, uri *url.URL) (string, error) {
+	return fn(ctx, uri)
+}
+
+// FileSecretResolver reads values from files referenced by file:///path URIs.
+// A single trailing newline is trimmed.
+type FileSecretResolver struct{}
+
+func (FileSecretResolver) ResolveSecret(_ context.Context, uri *url.URL) (string, error) {
+	data, err := os.ReadFile(uri.Path)
+	if err != nil {
+		return "", err
+	}
+
+	return trimNewline(string(data)), nil
+}
+
+// ExecSecretResolver runs commands referenced by exec://command?arg=a&arg=b URIs
+// and uses their standard output as values. A single trailing newline is trimmed.
+type ExecSecretResolver struct{}
+
+func (ExecSecretResolver) ResolveSecret(ctx context.Context, uri *url.URL) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, uri.Host+uri.Path, uri.Query()["arg"]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", errors.Wrapf(err, "run %s: %s", cmd.Path, strings.TrimSpace(stderr.String()))
+	}
+
+	return trimNewline(stdout.String()), nil
+}
+
+func trimNewline(value string) string {
+	value = strings.TrimSuffix(value, "\n")
+	return strings.TrimSuffix(value, "\r")
+}
+
+// secretStore resolves string values which are URIs with registered schemes.
+// Each distinct URI is resolved once.
+type secretStore struct {
+	options options
+	secrets map[string]*resolvedSecret
+}
+
+type resolvedSecret struct {
+	path  string
+	uri   *url.URL
+	value string
+	err   error
+}
+
+func newSecretStore(options options) *secretStore {
+	return &secretStore{options: options, secrets: make(map[string]*resolvedSecret)}
+}
+
+// resolveString resolves text if it is a secret URI. It is used for values copied by references,
+// which must be resolved before they are interpolated into other strings.
+func (s *secretStore) resolveString(ctx context.Context, path []string, text string) (string, error) {
+	uri, ok := s.options.secretURI(text)
+	if !ok {
+		return text, nil
+	}
+
+	secret, ok := s.secrets[text]
+	if !ok {
+		secret = &resolvedSecret{path: strings.Join(path, "."), uri: uri}
+		s.resolve(ctx, secret)
+		s.secrets[text] = secret
+	}
+
+	if secret.err != nil {
+		return "", errors.Wrapf(secret.err, "resolve %s on %s", secret.uri.Redacted(), secret.path)
+	}
+
+	return secret.value, nil
+}
+
+// resolveAll replaces secret URIs in allValues with resolved values.
+// URIs which have not been resolved yet are resolved concurrently.
+func (s *secretStore) resolveAll(ctx context.Context, allValues []map[string]any) error {
+	pending := make(map[string]*resolvedSecret)
+	for _, values := range allValues {
+		_, _ = transformStrings(values, nil, func(path []string, text string) (any, error) {
+			if _, ok := s.secrets[text]; ok {
+				return nil, nil
+			}
+
+			if uri, ok := s.options.secretURI(text); ok {
+				pending[text] = &resolvedSecret{path: strings.Join(path, "."), uri: uri}
+				s.secrets[text] = pending[text]
+			}
+
+			return nil, nil
+		})
+	}
+
+	var wg sync.WaitGroup
+	for _, secret := range pending {
+		secret := secret
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.resolve(ctx, secret)
+		}()
+	}
+
+	wg.Wait()
+	for i, values := range allValues {
+		result, err := transformStrings(values, nil, func(path []string, text string) (any, error) {
+			return s.resolveString(ctx, path, text)
+		})
+
+		if err != nil {
+			return err
+		}
+
+		allValues[i] = result.(map[string]any)
+	}
+
+	return nil
+}
+
+func (s *secretStore) resolve(ctx context.Context, secret *resolvedSecret) {
+	ctx, cancel := context.WithTimeout(ctx, s.options.secretTimeout)
+	defer cancel()
+	secret.value, secret.err = s.options.secretResolvers[secret.uri.Scheme].ResolveSecret(ctx, secret.uri)
+}
+
+func (o options) secretURI(text string) (*url.URL, bool) {
+	scheme, _, ok := strings.Cut(text, "://")
+	if !ok || o.secretResolvers[scheme] == nil {
+		return nil, false
+	}
+
+	uri, err := url.Parse(text)
+	if err != nil {
+		return nil, false
+	}
+
+	return uri, true
+}
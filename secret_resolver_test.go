@@ -0,0 +1,118 @@
+package confi_test
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jfk9w-go/confi"
+)
+
+func TestFromProvider_SecretResolvers(t *testing.T) {
+	type Config struct {
+		Password string   `yaml:"password"`
+		Token    string   `yaml:"token"`
+		Key      string   `yaml:"key"`
+		Echo     string   `yaml:"echo"`
+		Plain    string   `yaml:"plain"`
+		Copies   []string `yaml:"copies"`
+	}
+
+	dir := t.TempDir()
+	key := filepath.Join(dir, "key")
+	require.NoError(t, os.WriteFile(key, []byte("file-key\n"), 0o600))
+
+	var (
+		calls   atomic.Int32
+		started sync.WaitGroup
+	)
+
+	started.Add(2)
+	vault := confi.SecretResolverFunc(func(ctx context.Context, uri *url.URL) (string, error) {
+		calls.Add(1)
+		// both secrets must be resolved concurrently for this call to return
+		started.Done()
+		started.Wait()
+		return uri.Host + uri.Path + "#" + uri.Fragment, nil
+	})
+
+	actual, _, err := confi.FromProvider[Config](context.Background(), mockSourceProvider{
+		{"yaml", `{password: "secret://vault/kv/app#password", plain: "http://example.com"}`},
+		{"json", `{"token": "secret://vault/kv/app#token", "copies": ["secret://vault/kv/app#password"]}`},
+		{"yaml", `{key: "file://` + key + `", echo: "exec://echo?arg=hello"}`},
+	},
+		confi.WithSecretResolver("secret", vault),
+		confi.WithSecretResolver("file", confi.FileSecretResolver{}),
+		confi.WithSecretResolver("exec", confi.ExecSecretResolver{}),
+	)
+
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		Password: "vault/kv/app#password",
+		Token:    "vault/kv/app#token",
+		Key:      "file-key",
+		Echo:     "hello",
+		Plain:    "http://example.com",
+		Copies:   []string{"vault/kv/app#password"},
+	}, *actual)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestFromProvider_SecretResolvers_References(t *testing.T) {
+	type Config struct {
+		Password string `yaml:"password"`
+		DSN      string `yaml:"dsn"`
+		Copy     string `yaml:"copy"`
+	}
+
+	var calls atomic.Int32
+	vault := confi.SecretResolverFunc(func(ctx context.Context, uri *url.URL) (string, error) {
+		calls.Add(1)
+		return "p@ss$${ref:copy}", nil
+	})
+
+	actual, _, err := confi.FromProvider[Config](context.Background(), mockSourceProvider{
+		{"yaml", `{password: "secret://x/y", dsn: "pg://u:${ref:password}@h", copy: "${ref:password}"}`},
+	},
+		confi.WithReferences(),
+		confi.WithSecretResolver("secret", vault),
+	)
+
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		Password: "p@ss$${ref:copy}",
+		DSN:      "pg://u:p@ss$${ref:copy}@h",
+		Copy:     "p@ss$${ref:copy}",
+	}, *actual)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestFromProvider_SecretResolvers_Timeout(t *testing.T) {
+	type Config struct {
+		Password string `yaml:"password"`
+	}
+
+	slow := confi.SecretResolverFunc(func(ctx context.Context, uri *url.URL) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+
+	_, _, err := confi.FromProvider[Config](context.Background(), mockSourceProvider{
+		{"yaml", `{password: "secret://vault/kv/app#password"}`},
+	},
+		confi.WithSecretResolver("secret", slow),
+		confi.WithSecretTimeout(10*time.Millisecond),
+	)
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "on password")
+}
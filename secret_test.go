@@ -0,0 +1,92 @@
+package confi_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/jfk9w-go/confi"
+)
+
+func TestSecret(t *testing.T) {
+	type Config struct {
+		User     string                          `yaml:"user"`
+		Password confi.Secret[string]            `yaml:"password" default:"changeme" examples:"secret1, secret2"`
+		PIN      *confi.Secret[int]              `yaml:"pin,omitempty"`
+		Keys     map[string]confi.Secret[string] `yaml:"keys,omitempty"`
+	}
+
+	config, schema, err := confi.FromProvider[Config](context.Background(), mockSourceProvider{
+		{"yaml", `{user: admin, pin: 1234, keys: {a: key-a}}`},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "changeme", config.Password.Reveal())
+	assert.Equal(t, 1234, config.PIN.Reveal())
+	assert.Equal(t, "key-a", config.Keys["a"].Reveal())
+
+	t.Run("fmt", func(t *testing.T) {
+		for _, format := range []string{"%v", "%+v", "%#v", "%s", "%d", "%x", "%q"} {
+			output := fmt.Sprintf(format, config)
+			assert.NotContains(t, output, "changeme", format)
+			assert.NotContains(t, output, "1234", format)
+			assert.NotContains(t, output, "key-a", format)
+			assert.NotContains(t, output, "4d2", format)
+		}
+
+		assert.Equal(t, confi.Redacted, config.Password.String())
+	})
+
+	t.Run("marshal", func(t *testing.T) {
+		for name, codec := range map[string]confi.Codec{"json": confi.JSON, "yaml": confi.YAML} {
+			var b bytes.Buffer
+			require.NoError(t, codec.Marshal(config, &b), name)
+			assert.NotContains(t, b.String(), "changeme", name)
+			assert.Contains(t, b.String(), confi.Redacted, name)
+		}
+
+		data, err := json.Marshal(config)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "changeme")
+
+		data, err = yaml.Marshal(config)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "changeme")
+	})
+
+	t.Run("slog", func(t *testing.T) {
+		var b bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&b, nil))
+		logger.Info("loaded", "password", config.Password, "pin", config.PIN)
+		assert.NotContains(t, b.String(), "changeme")
+		assert.NotContains(t, b.String(), "1234")
+	})
+
+	t.Run("schema", func(t *testing.T) {
+		password := schema.Lookup("password")
+		require.NotNil(t, password)
+		assert.Equal(t, "string", password.Type)
+		assert.True(t, password.WriteOnly)
+		assert.True(t, schema.Lookup("pin").WriteOnly)
+		assert.Equal(t, "integer", schema.Lookup("pin").Type)
+
+		data, err := json.Marshal(schema)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "changeme")
+		assert.NotContains(t, string(data), "secret1")
+	})
+
+	t.Run("unmarshal json", func(t *testing.T) {
+		var secret confi.Secret[[]int]
+		require.NoError(t, json.Unmarshal([]byte(`[1, 2]`), &secret))
+		assert.Equal(t, []int{1, 2}, secret.Reveal())
+		assert.Equal(t, []int{3}, confi.NewSecret([]int{3}).Reveal())
+	})
+}
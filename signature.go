@@ -0,0 +1,91 @@
+package confi
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// SignatureExt is appended to file path in order to get the path of its detached signature.
+const SignatureExt = ".sig"
+
+var (
+	// ErrUnsigned is reported when signature is required, but not found.
+	ErrUnsigned = errors.New("signature not found")
+
+	// ErrBadSignature is reported when signature does not match contents or any of the public keys.
+	ErrBadSignature = errors.New("signature mismatch")
+)
+
+// SignatureError is returned by InputSource when signature verification fails.
+// Err is either ErrUnsigned, ErrBadSignature or the error which occurred while reading the signature.
+type SignatureError struct {
+	Input Input
+	Err   error
+}
+
+func (e *SignatureError) Error() string {
+	if file, ok := e.Input.(File); ok {
+		return "verify signature of " + file.Path() + ": " + e.Err.Error()
+	}
+
+	return "verify signature: " + e.Err.Error()
+}
+
+func (e *SignatureError) Unwrap() error { return e.Err }
+
+// Sign returns base64-encoded ed25519 signature of data.
+func Sign(data []byte, key ed25519.PrivateKey) []byte {
+	signature := ed25519.Sign(key, data)
+	encoded := make([]byte, base64.StdEncoding.EncodedLen(len(signature)))
+	base64.StdEncoding.Encode(encoded, signature)
+	return append(encoded, '\n')
+}
+
+// SignFile writes detached signature of file at path to path + SignatureExt.
+func SignFile(path string, key ed25519.PrivateKey) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path+SignatureExt, Sign(data, key), 0o644)
+}
+
+// verifySignature checks that signature read from input is valid for data and any of keys.
+func verifySignature(data []byte, input Input, keys []ed25519.PublicKey) error {
+	if input == nil {
+		return ErrUnsigned
+	}
+
+	reader, err := input.Reader()
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrUnsigned
+	} else if err != nil {
+		return errors.Wrap(err, "open signature")
+	}
+
+	defer CloseQuietly(reader)
+
+	encoded, err := io.ReadAll(reader)
+	if err != nil {
+		return errors.Wrap(err, "read signature")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(encoded)))
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return ErrBadSignature
+	}
+
+	for _, key := range keys {
+		if ed25519.Verify(key, data, signature) {
+			return nil
+		}
+	}
+
+	return ErrBadSignature
+}
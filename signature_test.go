@@ -0,0 +1,149 @@
+package confi_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jfk9w-go/confi"
+)
+
+func TestInputSource_GetValues_Signature(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	otherPublic, otherPrivate, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	signed := write("signed.yaml", "key: value")
+	require.NoError(t, confi.SignFile(signed, private))
+	unsigned := write("unsigned.yaml", "key: value")
+	tampered := write("tampered.yaml", "key: value")
+	require.NoError(t, confi.SignFile(tampered, private))
+	write("tampered.yaml", "key: other")
+	foreign := write("foreign.yaml", "key: value")
+	require.NoError(t, confi.SignFile(foreign, otherPrivate))
+
+	tests := []struct {
+		name     string
+		source   confi.InputSource
+		expected error
+	}{
+		{
+			name:   "signed",
+			source: confi.InputSource{Input: confi.File(signed), Format: "yaml", PublicKeys: []ed25519.PublicKey{public}},
+		},
+		{
+			name:   "any of keys",
+			source: confi.InputSource{Input: confi.File(foreign), Format: "yaml", PublicKeys: []ed25519.PublicKey{public, otherPublic}},
+		},
+		{
+			name:   "not verified",
+			source: confi.InputSource{Input: confi.File(unsigned), Format: "yaml"},
+		},
+		{
+			name: "reader",
+			source: confi.InputSource{
+				Input:      confi.Reader{R: bytes.NewReader([]byte("key: value"))},
+				Format:     "yaml",
+				PublicKeys: []ed25519.PublicKey{public},
+				Signature:  confi.Reader{R: bytes.NewReader(confi.Sign([]byte("key: value"), private))},
+			},
+		},
+		{
+			name:     "unsigned",
+			source:   confi.InputSource{Input: confi.File(unsigned), Format: "yaml", PublicKeys: []ed25519.PublicKey{public}},
+			expected: confi.ErrUnsigned,
+		},
+		{
+			name: "unsigned reader",
+			source: confi.InputSource{
+				Input:      confi.Reader{R: bytes.NewReader([]byte("key: value"))},
+				Format:     "yaml",
+				PublicKeys: []ed25519.PublicKey{public},
+			},
+			expected: confi.ErrUnsigned,
+		},
+		{
+			name:     "tampered",
+			source:   confi.InputSource{Input: confi.File(tampered), Format: "yaml", PublicKeys: []ed25519.PublicKey{public}},
+			expected: confi.ErrBadSignature,
+		},
+		{
+			name:     "foreign",
+			source:   confi.InputSource{Input: confi.File(foreign), Format: "yaml", PublicKeys: []ed25519.PublicKey{public}},
+			expected: confi.ErrBadSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := tt.source.GetValues(context.Background())
+			if tt.expected == nil {
+				require.NoError(t, err)
+				assert.Equal(t, map[string]any{"key": "value"}, values)
+				return
+			}
+
+			var signatureErr *confi.SignatureError
+			require.True(t, errors.As(err, &signatureErr), "%v", err)
+			assert.ErrorIs(t, err, tt.expected)
+		})
+	}
+
+	t.Run("provider", func(t *testing.T) {
+		type Config struct {
+			Key string `yaml:"key"`
+		}
+
+		config, _, err := confi.FromProvider[Config](context.Background(), &confi.DefaultSourceProvider{
+			Args:       []string{"--config.file=" + signed},
+			PublicKeys: []ed25519.PublicKey{public},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "value", config.Key)
+
+		_, _, err = confi.FromProvider[Config](context.Background(), &confi.DefaultSourceProvider{
+			Args:       []string{"--config.file=" + signed, "--config.file=" + unsigned},
+			PublicKeys: []ed25519.PublicKey{public},
+		})
+
+		assert.ErrorIs(t, err, confi.ErrUnsigned)
+		assert.Contains(t, err.Error(), "get values from "+unsigned+": verify signature")
+	})
+
+	t.Run("stdin", func(t *testing.T) {
+		type Config struct {
+			Key string `yaml:"key"`
+		}
+
+		provider := &confi.DefaultSourceProvider{
+			Args:       []string{"--config.stdin=yaml"},
+			Stdin:      bytes.NewReader([]byte("key: value")),
+			PublicKeys: []ed25519.PublicKey{public},
+		}
+
+		_, _, err := confi.FromProvider[Config](context.Background(), provider)
+		assert.ErrorIs(t, err, confi.ErrUnsigned)
+
+		provider.Stdin = bytes.NewReader([]byte("key: value"))
+		provider.Args = append(provider.Args, "--config.stdin.sig="+write("stdin.sig", string(confi.Sign([]byte("key: value"), private))))
+		config, _, err := confi.FromProvider[Config](context.Background(), provider)
+		require.NoError(t, err)
+		assert.Equal(t, "value", config.Key)
+	})
+}
@@ -1,7 +1,11 @@
 package confi
 
 import (
+	"bytes"
 	"context"
+	"crypto/ed25519"
+	"io"
+	"os"
 
 	"github.com/pkg/errors"
 )
@@ -41,9 +45,57 @@ func (s PropertySource) GetValues(ctx context.Context) (map[string]any, error) {
 type InputSource struct {
 	Input  Input
 	Format string
+
+	// Cipher decrypts encrypted values (see Cipher.Encrypt).
+	// If it is nil, encrypted values result in an error.
+	Cipher *Cipher
+
+	// PublicKeys are used to verify detached signature of Input (see SignFile) before decoding.
+	// If it is empty, signature is not checked.
+	PublicKeys []ed25519.PublicKey
+
+	// Signature provides the signature of Input.
+	// If it is nil and Input is File or FSFile, signature is read from the file with SignatureExt appended.
+	Signature Input
+}
+
+// String returns the path of Input if it is a file, so that errors do not print source contents.
+func (s InputSource) String() string {
+	switch input := s.Input.(type) {
+	case File:
+		return input.Path()
+	case FSFile:
+		return input.Path
+	case Reader:
+		if file, ok := input.R.(*os.File); ok {
+			return file.Name()
+		}
+	}
+
+	return s.Format + " input"
 }
 
 func (s InputSource) GetValues(ctx context.Context) (map[string]any, error) {
+	values, _, err := s.getValues(ctx)
+	return values, err
+}
+
+// getValues returns decrypted values along with raw values as they are read from input.
+func (s InputSource) getValues(ctx context.Context) (values, raw map[string]any, err error) {
+	raw, err = s.readValues(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values, err = decryptValues(s.Cipher, raw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return values, raw, nil
+}
+
+func (s InputSource) readValues(ctx context.Context) (map[string]any, error) {
 	reader, err := s.Input.Reader()
 	if err != nil {
 		return nil, errors.Wrap(err, "open input")
@@ -51,6 +103,29 @@ func (s InputSource) GetValues(ctx context.Context) (map[string]any, error) {
 
 	defer CloseQuietly(reader)
 
+	if len(s.PublicKeys) > 0 {
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, errors.Wrap(err, "read input")
+		}
+
+		signature := s.Signature
+		if signature == nil {
+			switch input := s.Input.(type) {
+			case File:
+				signature = File(input.Path() + SignatureExt)
+			case FSFile:
+				signature = FSFile{FS: input.FS, Path: input.Path + SignatureExt}
+			}
+		}
+
+		if err := verifySignature(data, signature, s.PublicKeys); err != nil {
+			return nil, &SignatureError{Input: s.Input, Err: err}
+		}
+
+		reader = bytes.NewReader(data)
+	}
+
 	if s.Format == "properties" {
 		props, err := readProperties(reader)
 		if err != nil {
@@ -62,7 +137,7 @@ func (s InputSource) GetValues(ctx context.Context) (map[string]any, error) {
 
 	codec, ok := Codecs[s.Format]
 	if !ok {
-		return nil, errors.Wrapf(err, "no codec found for %s", s.Format)
+		return nil, errors.Errorf("no codec found for %s", s.Format)
 	}
 
 	values := make(map[string]any)
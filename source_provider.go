@@ -2,7 +2,9 @@ package confi
 
 import (
 	"context"
+	"crypto/ed25519"
 	"io"
+	"os"
 	"path/filepath"
 	"strings"
 
@@ -13,11 +15,45 @@ type SourceProvider interface {
 	GetSources(ctx context.Context) ([]Source, error)
 }
 
+// SourceProviderFunc is a function implementing SourceProvider.
+type SourceProviderFunc func(ctx context.Context) ([]Source, error)
+
+func (fn SourceProviderFunc) GetSources(ctx context.Context) ([]Source, error) {
+	return fn(ctx)
+}
+
 type DefaultSourceProvider struct {
 	EnvPrefix string
 	Env       []string
 	Args      []string
 	Stdin     io.Reader
+
+	// Schema is used to detect properties which may be read from files
+	// via <key>_FILE environment variables and --<key>.file options (see Schema.AllowFile).
+	Schema *Schema
+
+	// Cipher decrypts encrypted values in configuration files and stdin.
+	// It may also be set via <prefix>_CONFIG_KEY environment variable
+	// or loaded from a key file specified via --config.key.file option or <prefix>_CONFIG_KEY_FILE environment variable.
+	Cipher *Cipher
+
+	// PublicKeys are used to verify detached signatures of configuration files (see SignFile).
+	// If it is not empty, unsigned files are refused.
+	PublicKeys []ed25519.PublicKey
+
+	// Base sources have the lowest priority and are overridden by all other sources.
+	// See FSSources for using files embedded into the binary.
+	Base []Source
+
+	// SearchPaths are glob patterns of configuration files which are loaded (if they exist)
+	// when no configuration files are specified explicitly.
+	// Get sets them to DefaultSearchPaths.
+	SearchPaths []string
+
+	// CredentialsDirectory is the systemd credentials directory (see CredentialsSource).
+	// Credentials override configuration files and stdin, but are overridden by command-line options.
+	// Get sets it from $CREDENTIALS_DIRECTORY.
+	CredentialsDirectory string
 }
 
 func (p *DefaultSourceProvider) GetSources(ctx context.Context) ([]Source, error) {
@@ -46,11 +82,25 @@ func (p *DefaultSourceProvider) GetSources(ctx context.Context) ([]Source, error
 		}
 	}
 
+	if err := SecretPolicyFromContext(ctx).checkArgs(ctx, argProps, p.Schema); err != nil {
+		return nil, errors.Wrap(err, "check secrets")
+	}
+
+	for _, props := range [][]Property{envProps, argProps} {
+		for i, prop := range props {
+			if err := p.readFileProperty(&props[i]); err != nil {
+				return nil, errors.Wrapf(err, "read %s", prop.Key())
+			}
+		}
+	}
+
 	var (
-		envs  PropertySource
-		files []Source
-		stdin *InputSource
-		args  PropertySource
+		envs     PropertySource
+		files    []Property
+		stdin    *InputSource
+		stdinSig Input
+		args     PropertySource
+		cipher   = p.Cipher
 	)
 
 	for _, item := range []struct {
@@ -62,22 +112,17 @@ func (p *DefaultSourceProvider) GetSources(ctx context.Context) ([]Source, error
 	} {
 		hasFiles := false
 		for _, prop := range item.props {
-			switch prop.Key() {
-			case "config.file":
+			switch strings.ToLower(prop.Key()) {
+			case "config.file", "config.dir":
 				if !hasFiles {
-					files = make([]Source, 0)
+					files = make([]Property, 0)
 				}
 
-				format := filepath.Ext(prop.Value)
-				if format == "" {
+				if prop.Value == "" {
 					continue
 				}
 
-				files = append(files, InputSource{
-					Input:  File(prop.Value),
-					Format: format[1:],
-				})
-
+				files = append(files, prop)
 				hasFiles = true
 
 			case "config.stdin":
@@ -90,25 +135,194 @@ func (p *DefaultSourceProvider) GetSources(ctx context.Context) ([]Source, error
 					}
 				}
 
+			case "config.stdin.sig":
+				stdinSig = nil
+				if prop.Value != "" {
+					stdinSig = File(prop.Value)
+				}
+
+			case "config.key":
+				if item.source == &args {
+					// command lines are visible to other users, so the key may be passed only via environment or file
+					return nil, errors.New("config.key must not be passed in command line, use --config.key.file")
+				}
+
+				var err error
+				cipher, err = NewCipher(prop.Value)
+				if err != nil {
+					return nil, errors.Wrap(err, "create cipher")
+				}
+
+			case "config.key.file":
+				var err error
+				cipher, err = LoadCipher(prop.Value)
+				if err != nil {
+					return nil, errors.Wrapf(err, "load key from %s", prop.Value)
+				}
+
 			default:
 				*item.source = append(*item.source, prop)
 			}
 		}
 	}
 
-	sources := make([]Source, 0)
+	sources := append(make([]Source, 0), p.Base...)
 	if envs != nil {
 		sources = append(sources, envs)
 	}
 
-	sources = append(sources, files...)
+	if files == nil {
+		for _, path := range p.SearchPaths {
+			files = append(files, Property{Path: []string{"config", "file"}, Value: "?" + path})
+		}
+	}
+
+	for _, prop := range files {
+		inputs, err := p.getInputs(ctx, strings.ToLower(prop.Key()), prop.Value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "get %s %s", prop.Key(), prop.Value)
+		}
+
+		for _, input := range inputs {
+			input.Cipher = cipher
+			input.PublicKeys = p.PublicKeys
+			sources = append(sources, input)
+		}
+	}
+
 	if stdin != nil {
+		// stdin has no path to derive signature path from, so it must be passed explicitly when keys are configured
+		stdin.Cipher = cipher
+		stdin.PublicKeys = p.PublicKeys
+		stdin.Signature = stdinSig
 		sources = append(sources, *stdin)
 	}
 
+	if p.CredentialsDirectory != "" {
+		sources = append(sources, CredentialsSource{Dir: p.CredentialsDirectory})
+	}
+
 	if args != nil {
 		sources = append(sources, args)
 	}
 
 	return sources, nil
 }
+
+// DefaultSearchPaths returns configuration file locations for application with name appName
+// in priority order: /etc/<app>/config.*, $XDG_CONFIG_HOME/<app>/config.* and ./<app>.yaml.
+// $XDG_CONFIG_HOME defaults to ~/.config on all platforms, as in XDG Base Directory Specification.
+func DefaultSearchPaths(appName string) []string {
+	paths := []string{filepath.Join("/etc", appName, "config.*")}
+	if dir := xdgConfigHome(); dir != "" {
+		paths = append(paths, filepath.Join(dir, appName, "config.*"))
+	}
+
+	return append(paths, appName+".yaml")
+}
+
+func xdgConfigHome() string {
+	// relative paths are invalid according to the specification and are ignored
+	if dir := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(dir) {
+		return dir
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".config")
+	}
+
+	return ""
+}
+
+// getInputs returns configuration files specified by config.file or config.dir property.
+// config.file may be a glob pattern (see glob) which must match at least one file unless prefixed with "?".
+// Plain paths prefixed with "?" are skipped if the file does not exist.
+// Files in config.dir and glob matches are sorted lexically, and files with unsupported extensions are skipped with a warning.
+func (p *DefaultSourceProvider) getInputs(ctx context.Context, key, value string) ([]InputSource, error) {
+	var (
+		paths    = []string{value}
+		multiple = false
+	)
+
+	switch {
+	case key == "config.dir":
+		entries, err := os.ReadDir(value)
+		if err != nil {
+			return nil, err
+		}
+
+		paths, multiple = paths[:0], true
+		for _, entry := range entries {
+			if !entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+				paths = append(paths, filepath.Join(value, entry.Name()))
+			}
+		}
+
+	case strings.HasPrefix(value, "?") || isGlob(value):
+		optional := strings.HasPrefix(value, "?")
+		pattern := strings.TrimPrefix(value, "?")
+
+		var err error
+		paths, err = glob(pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "glob %s", pattern)
+		}
+
+		if len(paths) == 0 && !optional {
+			return nil, errors.Errorf("no files match %s", pattern)
+		}
+
+		multiple = true
+	}
+
+	inputs := make([]InputSource, 0, len(paths))
+	for _, path := range paths {
+		if multiple && strings.HasSuffix(path, SignatureExt) {
+			continue
+		}
+
+		format := strings.TrimPrefix(filepath.Ext(path), ".")
+		if format == "" || multiple && !isSupportedFormat(format) {
+			Warn(ctx, Warning{
+				Code:    WarnUnsupportedFile,
+				Source:  path,
+				Message: "unsupported file format, skipped",
+			})
+
+			continue
+		}
+
+		inputs = append(inputs, InputSource{
+			Input:  File(path),
+			Format: format,
+		})
+	}
+
+	return inputs, nil
+}
+
+func isSupportedFormat(format string) bool {
+	_, ok := Codecs[format]
+	return ok || format == "properties"
+}
+
+func (p *DefaultSourceProvider) readFileProperty(prop *Property) error {
+	last := len(prop.Path) - 1
+	if p.Schema == nil || last < 1 || !strings.EqualFold(prop.Path[last], "file") {
+		return nil
+	}
+
+	schema, path := p.Schema.lookup(prop.Path[:last])
+	if schema == nil || !schema.AllowFile {
+		return nil
+	}
+
+	data, err := os.ReadFile(prop.Value)
+	if err != nil {
+		return err
+	}
+
+	// path is rewritten to property names from the schema, since keys are matched case-sensitively
+	*prop = Property{Path: path, Value: trimNewline(string(data))}
+	return nil
+}
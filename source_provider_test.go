@@ -3,6 +3,8 @@ package confi_test
 import (
 	"bytes"
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -121,3 +123,167 @@ func TestDefaultSourceProvider_GetSources(t *testing.T) {
 		})
 	}
 }
+
+func TestDefaultSourceProvider_GetSources_Files(t *testing.T) {
+	type Config struct {
+		DB struct {
+			User     string `yaml:"user"`
+			Password string `yaml:"password" file:"true"`
+		} `yaml:"db"`
+		Token string `yaml:"token" file:"true"`
+	}
+
+	schema, err := confi.GenerateSchema(Config{})
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	password := filepath.Join(dir, "password")
+	require.NoError(t, os.WriteFile(password, []byte("secret\n"), 0o600))
+	token := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(token, []byte("token\r\n"), 0o600))
+
+	provider := &confi.DefaultSourceProvider{
+		EnvPrefix: "test_app_",
+		Env: []string{
+			"test_app_DB_PASSWORD_FILE=" + password,
+			"test_app_db_user_file=/not/read",
+		},
+		Args: []string{
+			"--token.file=" + token,
+		},
+		Schema: schema,
+	}
+
+	sources, err := provider.GetSources(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []confi.Source{
+		confi.PropertySource{
+			{Path: []string{"db", "password"}, Value: "secret"},
+			{Path: []string{"db", "user", "file"}, Value: "/not/read"},
+		},
+		confi.PropertySource{
+			{Path: []string{"token"}, Value: "token"},
+		},
+	}, sources)
+
+	provider.Args = []string{"--token.file=" + filepath.Join(dir, "missing")}
+	_, err = provider.GetSources(context.Background())
+	assert.Error(t, err)
+
+	config, _, err := confi.FromProvider[Config](context.Background(), &confi.DefaultSourceProvider{
+		EnvPrefix: "APP_",
+		Env:       []string{"APP_DB_PASSWORD_FILE=" + password},
+		Schema:    schema,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "secret", config.DB.Password)
+}
+
+func TestDefaultSourceProvider_GetSources_Dir(t *testing.T) {
+	dir := t.TempDir()
+	confd := filepath.Join(dir, "conf.d")
+	require.NoError(t, os.Mkdir(confd, 0o755))
+	require.NoError(t, os.Mkdir(filepath.Join(confd, "nested.yaml"), 0o755))
+	for name, content := range map[string]string{
+		"10-base.yaml":       "{a: base, b: base, c: base}",
+		"20-override.json":   `{"b": "override"}`,
+		"30-last.properties": "c=last",
+		"README.md":          "readme",
+		"backup":             "a: backup",
+		".hidden.yaml":       "a: hidden",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(confd, name), []byte(content), 0o644))
+	}
+
+	file := filepath.Join(dir, "file.yaml")
+	require.NoError(t, os.WriteFile(file, []byte("{c: file}"), 0o644))
+
+	provider := &confi.DefaultSourceProvider{
+		EnvPrefix: "test_app_",
+		Env:       []string{"test_app_config_dir=" + confd},
+	}
+
+	sources, err := provider.GetSources(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []confi.Source{
+		confi.InputSource{Input: confi.File(filepath.Join(confd, "10-base.yaml")), Format: "yaml"},
+		confi.InputSource{Input: confi.File(filepath.Join(confd, "20-override.json")), Format: "json"},
+		confi.InputSource{Input: confi.File(filepath.Join(confd, "30-last.properties")), Format: "properties"},
+	}, sources)
+
+	type Config struct {
+		A, B, C string
+	}
+
+	var warnings []confi.Warning
+	provider.Args = []string{"--config.dir=" + confd, "--config.file=" + file, "--config.file=" + filepath.Join(dir, "noext")}
+	config, _, err := confi.FromProvider[Config](context.Background(), provider,
+		confi.WithWarningHandler(func(warning confi.Warning) { warnings = append(warnings, warning) }))
+
+	require.NoError(t, err)
+	assert.Equal(t, &Config{A: "base", B: "override", C: "file"}, config)
+	assert.Equal(t, []confi.Warning{
+		{Code: confi.WarnUnsupportedFile, Source: filepath.Join(confd, "README.md"), Message: "unsupported file format, skipped"},
+		{Code: confi.WarnUnsupportedFile, Source: filepath.Join(confd, "backup"), Message: "unsupported file format, skipped"},
+		{Code: confi.WarnUnsupportedFile, Source: filepath.Join(dir, "noext"), Message: "unsupported file format, skipped"},
+	}, warnings)
+
+	provider.Args = []string{"--config.dir=" + filepath.Join(dir, "missing")}
+	_, err = provider.GetSources(context.Background())
+	assert.Error(t, err)
+}
+
+func TestDefaultSourceProvider_GetSources_SearchPaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"etc/app/config.yaml", "etc/app/config.yaml.sig", "home/app/config.json", "app.yaml"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte("{}"), 0o644))
+	}
+
+	provider := &confi.DefaultSourceProvider{
+		SearchPaths: []string{
+			filepath.Join(dir, "etc", "app", "config.*"),
+			filepath.Join(dir, "missing", "app", "config.*"),
+			filepath.Join(dir, "home", "app", "config.*"),
+			filepath.Join(dir, "app.yaml"),
+			filepath.Join(dir, "missing.yaml"),
+		},
+	}
+
+	sources, err := provider.GetSources(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []confi.Source{
+		confi.InputSource{Input: confi.File(filepath.Join(dir, "etc", "app", "config.yaml")), Format: "yaml"},
+		confi.InputSource{Input: confi.File(filepath.Join(dir, "home", "app", "config.json")), Format: "json"},
+		confi.InputSource{Input: confi.File(filepath.Join(dir, "app.yaml")), Format: "yaml"},
+	}, sources)
+
+	provider.Args = []string{"--config.file=?" + filepath.Join(dir, "missing.yaml")}
+	sources, err = provider.GetSources(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, sources)
+
+	provider.Args = []string{"--config.file=" + filepath.Join(dir, "missing.yaml")}
+	sources, err = provider.GetSources(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []confi.Source{
+		confi.InputSource{Input: confi.File(filepath.Join(dir, "missing.yaml")), Format: "yaml"},
+	}, sources)
+
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "home"))
+	assert.Equal(t, []string{
+		filepath.Join("/etc", "app", "config.*"),
+		filepath.Join(dir, "home", "app", "config.*"),
+		"app.yaml",
+	}, confi.DefaultSearchPaths("app"))
+
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", filepath.Join(dir, "user"))
+	assert.Equal(t, []string{
+		filepath.Join("/etc", "app", "config.*"),
+		filepath.Join(dir, "user", ".config", "app", "config.*"),
+		"app.yaml",
+	}, confi.DefaultSearchPaths("app"))
+}
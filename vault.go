@@ -0,0 +1,333 @@
+package confi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultVaultAddress is used by VaultSource when neither Address nor VAULT_ADDR is set.
+	DefaultVaultAddress = "https://127.0.0.1:8200"
+	// DefaultVaultMount is the default mount path of KV v2 secrets engine.
+	DefaultVaultMount = "secret"
+)
+
+// VaultSecret describes a secret read by VaultSource.
+type VaultSecret struct {
+	// Path of the secret relative to the mount (e.g. "app/db").
+	Path string
+
+	// Prefix is the property path where secret data is mounted (e.g. "db.credentials").
+	// Secret data is merged into the root if it is empty.
+	Prefix string
+
+	// Version of the secret to read. The latest version is read if it is zero.
+	Version int
+}
+
+// VaultMetadata is version metadata of a secret read by VaultSource.
+type VaultMetadata struct {
+	Version        int               `json:"version"`
+	CreatedTime    time.Time         `json:"created_time"`
+	DeletionTime   string            `json:"deletion_time"`
+	Destroyed      bool              `json:"destroyed"`
+	CustomMetadata map[string]string `json:"custom_metadata"`
+}
+
+// VaultSource reads secrets from HashiCorp Vault KV v2 secrets engine.
+// The token is taken from Token, VAULT_TOKEN environment variable or TokenFile (in this order).
+// The source keeps token lease and secret versions between calls, so it must be used by pointer.
+type VaultSource struct {
+	// Address of Vault (VAULT_ADDR environment variable or DefaultVaultAddress by default).
+	Address string
+
+	// Token used for authentication.
+	Token string
+
+	// TokenFile contains the token (e.g. written by Vault Agent). It is read on each request.
+	TokenFile string
+
+	// Mount is the mount path of KV v2 secrets engine (DefaultVaultMount by default).
+	Mount string
+
+	// Namespace is sent in X-Vault-Namespace header, if set.
+	Namespace string
+
+	// Secrets to read. Secrets are merged in order.
+	Secrets []VaultSecret
+
+	// RenewToken enables token renewal (via renew-self) when half of its lease has passed.
+	// Renewal failures are reported as warnings.
+	RenewToken bool
+
+	// Client is used for requests (http.DefaultClient by default).
+	Client *http.Client
+
+	// PollInterval is the interval of checking secret versions in Watch (DefaultPollInterval by default).
+	PollInterval time.Duration
+
+	mu           sync.Mutex
+	versions     map[string]VaultMetadata
+	renewAt      time.Time
+	renewStopped bool
+}
+
+func (s *VaultSource) GetValues(ctx context.Context) (map[string]any, error) {
+	if err := s.renewToken(ctx); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]any)
+	versions := make(map[string]VaultMetadata, len(s.Secrets))
+	for _, secret := range s.Secrets {
+		var resp struct {
+			Data struct {
+				Data     map[string]any `json:"data"`
+				Metadata VaultMetadata  `json:"metadata"`
+			} `json:"data"`
+		}
+
+		query := url.Values{}
+		if secret.Version > 0 {
+			query.Set("version", strconv.Itoa(secret.Version))
+		}
+
+		if err := s.request(ctx, http.MethodGet, s.secretPath("data", secret.Path), query, &resp); err != nil {
+			return nil, errors.Wrapf(err, "read secret %s", secret.Path)
+		}
+
+		data := any(resp.Data.Data)
+		if secret.Prefix != "" {
+			for _, name := range reverse(strings.Split(secret.Prefix, ".")) {
+				data = map[string]any{name: data}
+			}
+		}
+
+		values = mergeValues(values, data).(map[string]any)
+		versions[secret.Path] = resp.Data.Metadata
+	}
+
+	s.mu.Lock()
+	s.versions = versions
+	s.mu.Unlock()
+
+	return values, nil
+}
+
+// Versions returns version metadata of secrets read by the last GetValues call by secret path.
+func (s *VaultSource) Versions() map[string]VaultMetadata {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions := make(map[string]VaultMetadata, len(s.versions))
+	for path, metadata := range s.versions {
+		versions[path] = metadata
+	}
+
+	return versions
+}
+
+// Watch polls metadata of secrets until current version of any secret without pinned Version
+// differs from the version read by the last GetValues call. The token is renewed while watching if RenewToken is set.
+// Failed requests are ignored, so that temporary unavailability does not stop watching.
+func (s *VaultSource) Watch(ctx context.Context) error {
+	interval := s.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if changed, err := s.changed(ctx); err == nil && changed {
+				return nil
+			}
+		}
+	}
+}
+
+func (s *VaultSource) String() string {
+	paths := make([]string, len(s.Secrets))
+	for i, secret := range s.Secrets {
+		paths[i] = secret.Path
+	}
+
+	return "vault " + strings.Join(paths, ", ")
+}
+
+func (s *VaultSource) changed(ctx context.Context) (bool, error) {
+	if err := s.renewToken(ctx); err != nil {
+		return false, err
+	}
+
+	versions := s.Versions()
+	for _, secret := range s.Secrets {
+		if secret.Version > 0 {
+			continue
+		}
+
+		var resp struct {
+			Data struct {
+				CurrentVersion int `json:"current_version"`
+			} `json:"data"`
+		}
+
+		if err := s.request(ctx, http.MethodGet, s.secretPath("metadata", secret.Path), nil, &resp); err != nil {
+			return false, errors.Wrapf(err, "read metadata of %s", secret.Path)
+		}
+
+		if resp.Data.CurrentVersion != versions[secret.Path].Version {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// renewToken renews the token if RenewToken is set and half of its lease has passed.
+func (s *VaultSource) renewToken(ctx context.Context) error {
+	if !s.RenewToken {
+		return nil
+	}
+
+	s.mu.Lock()
+	renewAt, stopped := s.renewAt, s.renewStopped
+	s.mu.Unlock()
+	if stopped || time.Now().Before(renewAt) {
+		return nil
+	}
+
+	var resp struct {
+		Auth struct {
+			LeaseDuration int  `json:"lease_duration"`
+			Renewable     bool `json:"renewable"`
+		} `json:"auth"`
+	}
+
+	if err := s.request(ctx, http.MethodPost, "auth/token/renew-self", nil, &resp); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		Warn(ctx, Warning{
+			Code:    WarnVaultTokenRenewal,
+			Source:  s.String(),
+			Message: "renew token: " + err.Error(),
+		})
+
+		return nil
+	}
+
+	lease := time.Duration(resp.Auth.LeaseDuration) * time.Second
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if resp.Auth.Renewable && lease > 0 {
+		s.renewAt = time.Now().Add(lease / 2)
+	} else {
+		// the token does not expire or cannot be renewed anymore
+		s.renewStopped = true
+	}
+
+	return nil
+}
+
+func (s *VaultSource) secretPath(kind, path string) string {
+	mount := s.Mount
+	if mount == "" {
+		mount = DefaultVaultMount
+	}
+
+	return strings.Trim(mount, "/") + "/" + kind + "/" + strings.Trim(path, "/")
+}
+
+func (s *VaultSource) token() (string, error) {
+	if s.Token != "" {
+		return s.Token, nil
+	}
+
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	if s.TokenFile != "" {
+		data, err := os.ReadFile(s.TokenFile)
+		if err != nil {
+			return "", errors.Wrap(err, "read token file")
+		}
+
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	return "", errors.New("no token configured")
+}
+
+func (s *VaultSource) request(ctx context.Context, method, path string, query url.Values, result any) error {
+	token, err := s.token()
+	if err != nil {
+		return err
+	}
+
+	address := s.Address
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+
+	if address == "" {
+		address = DefaultVaultAddress
+	}
+
+	endpoint := strings.TrimSuffix(address, "/") + "/v1/" + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if method == http.MethodPost {
+		body = strings.NewReader("{}")
+	}
+
+	header := http.Header{"X-Vault-Token": {token}}
+	if s.Namespace != "" {
+		header.Set("X-Vault-Namespace", s.Namespace)
+	}
+
+	resp, err := sendRequest(ctx, s.Client, method, endpoint, body, header)
+	if err != nil {
+		return err
+	}
+
+	defer CloseQuietly(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp struct {
+			Errors []string `json:"errors"`
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err == nil && len(errResp.Errors) > 0 {
+			return errors.Errorf("unexpected status %s: %s", resp.Status, strings.Join(errResp.Errors, "; "))
+		}
+
+		return errors.Errorf("unexpected status %s", resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return errors.Wrap(err, "decode response")
+	}
+
+	return nil
+}
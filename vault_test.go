@@ -0,0 +1,212 @@
+package confi_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jfk9w-go/confi"
+)
+
+// fakeVault implements a subset of Vault KV v2 and token API.
+type fakeVault struct {
+	mu       sync.Mutex
+	token    string
+	secrets  map[string][]map[string]any
+	renewals int
+}
+
+func (v *fakeVault) put(path string, data map[string]any) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.secrets[path] = append(v.secrets[path], data)
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	reply := func(status int, body any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(body)
+	}
+
+	if r.Header.Get("X-Vault-Token") != v.token {
+		reply(http.StatusForbidden, map[string]any{"errors": []string{"permission denied"}})
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	if path == "auth/token/renew-self" && r.Method == http.MethodPost {
+		v.renewals++
+		reply(http.StatusOK, map[string]any{"auth": map[string]any{"lease_duration": 3600, "renewable": true}})
+		return
+	}
+
+	kind, path, _ := strings.Cut(strings.TrimPrefix(path, "kv/"), "/")
+	versions, ok := v.secrets[path]
+	if !ok || r.Method != http.MethodGet {
+		reply(http.StatusNotFound, map[string]any{"errors": []string{}})
+		return
+	}
+
+	switch kind {
+	case "data":
+		version := len(versions)
+		if text := r.URL.Query().Get("version"); text != "" {
+			version, _ = strconv.Atoi(text)
+		}
+
+		reply(http.StatusOK, map[string]any{
+			"data": map[string]any{
+				"data": versions[version-1],
+				"metadata": map[string]any{
+					"version":         version,
+					"created_time":    "2024-01-01T00:00:00Z",
+					"deletion_time":   "",
+					"destroyed":       false,
+					"custom_metadata": map[string]string{"owner": "ops"},
+				},
+			},
+			"lease_duration": 0,
+			"renewable":      false,
+		})
+
+	case "metadata":
+		reply(http.StatusOK, map[string]any{"data": map[string]any{"current_version": len(versions)}})
+
+	default:
+		reply(http.StatusNotFound, map[string]any{"errors": []string{}})
+	}
+}
+
+func TestVaultSource(t *testing.T) {
+	vault := &fakeVault{
+		token: "s.token",
+		secrets: map[string][]map[string]any{
+			"app/db":     {{"password": "old"}, {"user": "app", "password": "secret"}},
+			"app/common": {{"api_key": "key", "timeout": 30}},
+		},
+	}
+
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("s.token\n"), 0o600))
+
+	source := &confi.VaultSource{
+		Address:   server.URL,
+		TokenFile: tokenFile,
+		Mount:     "kv",
+		Secrets: []confi.VaultSecret{
+			{Path: "app/common"},
+			{Path: "app/db", Prefix: "db.credentials"},
+		},
+		RenewToken:   true,
+		PollInterval: 10 * time.Millisecond,
+	}
+
+	for i := 0; i < 2; i++ {
+		values, err := source.GetValues(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{
+			"api_key": "key",
+			"timeout": float64(30),
+			"db":      map[string]any{"credentials": map[string]any{"user": "app", "password": "secret"}},
+		}, values)
+	}
+
+	assert.Equal(t, 1, vault.renewals)
+	assert.Equal(t, confi.VaultMetadata{
+		Version:        2,
+		CreatedTime:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		CustomMetadata: map[string]string{"owner": "ops"},
+	}, source.Versions()["app/db"])
+
+	t.Run("pinned version", func(t *testing.T) {
+		values, err := (&confi.VaultSource{
+			Address: server.URL,
+			Token:   "s.token",
+			Mount:   "kv",
+			Secrets: []confi.VaultSecret{{Path: "app/db", Version: 1}},
+		}).GetValues(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"password": "old"}, values)
+	})
+
+	t.Run("env token", func(t *testing.T) {
+		t.Setenv("VAULT_TOKEN", "s.token")
+		t.Setenv("VAULT_ADDR", server.URL)
+		_, err := (&confi.VaultSource{Mount: "kv", Secrets: []confi.VaultSecret{{Path: "app/db"}}}).GetValues(context.Background())
+		require.NoError(t, err)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := (&confi.VaultSource{
+			Address: server.URL,
+			Token:   "invalid",
+			Mount:   "kv",
+			Secrets: []confi.VaultSecret{{Path: "app/db"}},
+		}).GetValues(context.Background())
+
+		assert.EqualError(t, err, "read secret app/db: unexpected status 403 Forbidden: permission denied")
+
+		_, err = (&confi.VaultSource{
+			Address: server.URL,
+			Token:   "s.token",
+			Mount:   "kv",
+			Secrets: []confi.VaultSecret{{Path: "app/missing"}},
+		}).GetValues(context.Background())
+
+		assert.EqualError(t, err, "read secret app/missing: unexpected status 404 Not Found")
+	})
+
+	t.Run("watch", func(t *testing.T) {
+		type Config struct {
+			DB struct {
+				Credentials struct {
+					Password confi.Secret[string] `yaml:"password"`
+				} `yaml:"credentials"`
+			} `yaml:"db"`
+		}
+
+		provider := confi.SourceProviderFunc(func(ctx context.Context) ([]confi.Source, error) {
+			return []confi.Source{source}, nil
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		passwords := make(chan string)
+		done := make(chan error)
+		go func() {
+			done <- confi.Watch[Config](ctx, provider, func(config *Config, _ *confi.Schema, err error) {
+				if assert.NoError(t, err) {
+					passwords <- config.DB.Credentials.Password.Reveal()
+				}
+			})
+		}()
+
+		assert.Equal(t, "secret", <-passwords)
+		vault.put("app/db", map[string]any{"user": "app", "password": "rotated"})
+		assert.Equal(t, "rotated", <-passwords)
+		assert.Equal(t, 3, source.Versions()["app/db"].Version)
+
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+	})
+}
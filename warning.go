@@ -0,0 +1,74 @@
+package confi
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Warning codes reported by this package.
+const (
+	WarnSecretInArgs         = "secret_in_args"
+	WarnSecretFilePermission = "secret_file_permission"
+	WarnUnsupportedFile      = "unsupported_file"
+	WarnVaultTokenRenewal    = "vault_token_renewal"
+	WarnStaleCache           = "stale_cache"
+	WarnCacheRead            = "cache_read"
+	WarnCacheWrite           = "cache_write"
+)
+
+// Warning describes a non-fatal problem found while loading configuration.
+type Warning struct {
+	// Code identifies the kind of the problem (see Warn* constants).
+	Code string
+	// Source describes where the problem was found (e.g. file path or "args").
+	Source string
+	// Path is the property path, if the problem relates to a single property.
+	Path string
+	// Message is a human-readable description.
+	Message string
+}
+
+func (w Warning) String() string {
+	text := w.Message
+	if w.Path != "" {
+		text = "on " + w.Path + ": " + text
+	}
+
+	if w.Source != "" {
+		text = w.Source + ": " + text
+	}
+
+	return text
+}
+
+type warningHandlerKey struct{}
+
+// WithWarningHandler sets handler for warnings reported while loading configuration.
+// By default, warnings are logged with slog.
+func WithWarningHandler(handler func(Warning)) Option {
+	return func(options *options) {
+		options.warningHandler = handler
+	}
+}
+
+// Warn reports warning to the handler set by WithWarningHandler.
+// It may be used by custom Source and SourceProvider implementations with ctx passed to them.
+func Warn(ctx context.Context, warning Warning) {
+	if handler, ok := ctx.Value(warningHandlerKey{}).(func(Warning)); ok {
+		handler(warning)
+		return
+	}
+
+	slog.WarnContext(ctx, warning.Message,
+		"code", warning.Code,
+		"source", warning.Source,
+		"path", warning.Path)
+}
+
+func withWarningHandler(ctx context.Context, handler func(Warning)) context.Context {
+	if handler == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, warningHandlerKey{}, handler)
+}
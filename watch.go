@@ -0,0 +1,106 @@
+package confi
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultPollInterval is used by sources which detect changes by polling when no interval is configured.
+	DefaultPollInterval = 10 * time.Second
+	// DefaultWatchBackoff is the delay before Watch retries to get sources from provider
+	// and before remote sources retry failed requests. It is doubled for each retry.
+	DefaultWatchBackoff = 500 * time.Millisecond
+	// MaxWatchBackoff limits the delay between retries of Watch and remote sources.
+	MaxWatchBackoff = time.Minute
+)
+
+// Watcher is implemented by sources which are able to detect changes of their values.
+type Watcher interface {
+	// Watch blocks until source values change (returning nil) or ctx is done (returning ctx.Err()).
+	// Other errors mean that changes cannot be tracked anymore.
+	Watch(ctx context.Context) error
+}
+
+// Watch loads configuration from provider and reloads it each time any of the sources implementing Watcher changes.
+// onLoad is called with each loaded configuration or load error.
+// If provider fails to get sources, the attempt is retried with exponential backoff (see DefaultWatchBackoff).
+// Watch blocks until ctx is done.
+func Watch[T any](ctx context.Context, provider SourceProvider, onLoad func(config *T, schema *Schema, err error), opts ...Option) error {
+	options := newOptions(opts)
+	ctx = withWarningHandler(ctx, options.warningHandler)
+	ctx = withSecretPolicy(ctx, options.secretPolicy)
+
+	var retry backoff
+	for {
+		sources, err := provider.GetSources(ctx)
+		if err != nil {
+			onLoad(nil, nil, errors.Wrap(err, "get sources"))
+
+			// there are no sources to watch, so the provider is asked again later
+			if err := retry.wait(ctx); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		retry.reset()
+		onLoad(fromSources[T](ctx, sources, options))
+		if err := waitForChange(ctx, sources, onLoad); err != nil {
+			return err
+		}
+	}
+}
+
+// waitForChange blocks until any of the sources changes.
+// Errors of Watcher are passed to onLoad, and the failed source is not watched anymore.
+func waitForChange[T any](ctx context.Context, sources []Source, onLoad func(*T, *Schema, error)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		source Source
+		err    error
+	}
+
+	results := make(chan result)
+	watching := 0
+	for _, source := range sources {
+		if watcher, ok := source.(Watcher); ok {
+			watching++
+			go func(source Source, watcher Watcher) {
+				results <- result{source: source, err: watcher.Watch(ctx)}
+			}(source, watcher)
+		}
+	}
+
+	defer func() {
+		// stop remaining watchers and wait for them to return
+		cancel()
+		for ; watching > 0; watching-- {
+			<-results
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case result := <-results:
+			watching--
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			if result.err == nil {
+				return nil
+			}
+
+			onLoad(nil, nil, errors.Wrapf(result.err, "watch %s", result.source))
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package confi_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jfk9w-go/confi"
+)
+
+type mockWatcher struct {
+	confi.PropertySource
+	changes chan error
+}
+
+func (w mockWatcher) Watch(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-w.changes:
+		return err
+	}
+}
+
+func TestWatch(t *testing.T) {
+	type Config struct {
+		Value int `yaml:"value"`
+	}
+
+	changes := make(chan error)
+	loads := 0
+	provider := confi.SourceProviderFunc(func(ctx context.Context) ([]confi.Source, error) {
+		loads++
+		return []confi.Source{
+			confi.PropertySource{{Path: []string{"value"}, Value: "0"}},
+			mockWatcher{
+				PropertySource: confi.PropertySource{{Path: []string{"value"}, Value: string(rune('0' + loads))}},
+				changes:        changes,
+			},
+		}, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	type result struct {
+		value int
+		err   error
+	}
+
+	results := make(chan result)
+	done := make(chan error)
+	go func() {
+		done <- confi.Watch[Config](ctx, provider, func(config *Config, _ *confi.Schema, err error) {
+			if err != nil {
+				results <- result{err: err}
+				return
+			}
+
+			results <- result{value: config.Value}
+		})
+	}()
+
+	assert.Equal(t, result{value: 1}, <-results)
+	changes <- nil
+	assert.Equal(t, result{value: 2}, <-results)
+
+	changes <- errors.New("connection lost")
+	failed := <-results
+	assert.ErrorContains(t, failed.err, "connection lost")
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestWatch_ProviderError(t *testing.T) {
+	type Config struct {
+		Value int `yaml:"value"`
+	}
+
+	calls := 0
+	provider := confi.SourceProviderFunc(func(ctx context.Context) ([]confi.Source, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("temporary failure")
+		}
+
+		return []confi.Source{confi.PropertySource{{Path: []string{"value"}, Value: "1"}}}, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	errs := make(chan error)
+	values := make(chan int)
+	done := make(chan error)
+	go func() {
+		done <- confi.Watch[Config](ctx, provider, func(config *Config, _ *confi.Schema, err error) {
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			values <- config.Value
+		})
+	}()
+
+	assert.ErrorContains(t, <-errs, "get sources: temporary failure")
+	assert.Equal(t, 1, <-values)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}